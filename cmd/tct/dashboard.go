@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neox5/tct/internal/dashboard"
+)
+
+// runDashboard prints a Grafana dashboard for the tct metrics as JSON.
+// Returns the process exit code.
+func runDashboard(args []string) int {
+	fs := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	modes := fs.String("modes", strings.Join(dashboard.Modes, ","), "comma-separated modes to include panels for")
+	datasource := fs.String("datasource", "", "Prometheus data source UID (chosen on import if empty)")
+	namespace := fs.String("namespace", "tct", "metric name prefix (TCT_METRICS_NAMESPACE)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	data, err := dashboard.Generate(strings.Split(*modes, ","), *datasource, *namespace)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neox5/tct/internal/app"
+)
+
+// runEnvDocs prints all environment variables with their types, defaults,
+// constraints, and modes as markdown or JSON. Returns the process exit code.
+func runEnvDocs(args []string) int {
+	fs := flag.NewFlagSet("env-docs", flag.ContinueOnError)
+	format := fs.String("format", "markdown", "output format ('markdown' or 'json')")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+
+	settings := app.Describe()
+	switch *format {
+	case "markdown":
+		fmt.Println("| Variable | Mode | Type | Default | Constraints |")
+		fmt.Println("|---|---|---|---|---|")
+		for _, s := range settings {
+			def := s.Default
+			if s.Required {
+				def = "required"
+			}
+			name := "`" + s.Key + "`"
+			for _, alias := range s.Aliases {
+				name += fmt.Sprintf(" (deprecated: `%s`)", alias)
+			}
+			fmt.Printf("| %s | %s | %s | %s | %s |\n", name, s.Mode, s.Type, code(def), code(strings.Join(s.Constraints, ", ")))
+		}
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(settings)
+	default:
+		fmt.Fprintf(os.Stderr, "invalid format %q (must be 'markdown' or 'json')\n", *format)
+		return 1
+	}
+	return 0
+}
+
+// code formats a non-empty markdown table value as inline code, escaping
+// pipes so they do not split the cell.
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(s, "|", "\\|") + "`"
+}
@@ -2,19 +2,51 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
+	"slices"
+	"sync/atomic"
 	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/neox5/tct/internal/accesslog"
 	"github.com/neox5/tct/internal/app"
+	"github.com/neox5/tct/internal/behavior"
+	"github.com/neox5/tct/internal/bodysize"
+	"github.com/neox5/tct/internal/brownout"
+	"github.com/neox5/tct/internal/certs"
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/echo"
+	"github.com/neox5/tct/internal/errbody"
+	"github.com/neox5/tct/internal/events"
 	"github.com/neox5/tct/internal/generator"
 	"github.com/neox5/tct/internal/handler"
+	"github.com/neox5/tct/internal/inspect"
 	"github.com/neox5/tct/internal/metrics"
+	"github.com/neox5/tct/internal/profiles"
+	"github.com/neox5/tct/internal/random"
+	"github.com/neox5/tct/internal/scenario"
+	"github.com/neox5/tct/internal/schedule"
 	"github.com/neox5/tct/internal/server"
+	"github.com/neox5/tct/internal/slo"
+	"github.com/neox5/tct/internal/telemetry"
+	"github.com/neox5/tct/internal/verdict"
 	"github.com/neox5/tct/internal/version"
 )
 
+// errVerdictFailed is returned by the sender if the run failed its
+// assertions.
+var errVerdictFailed = errors.New("run failed its assertions")
+
+// exitVerdictFailed is the exit code of a run that failed its assertions.
+const exitVerdictFailed = 3
+
 func main() {
 	// Handle --version flag
 	if len(os.Args) > 1 && os.Args[1] == "--version" {
@@ -22,46 +54,173 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Handle subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "env-docs":
+			os.Exit(runEnvDocs(os.Args[2:]))
+		case "top":
+			os.Exit(runTop(os.Args[2:]))
+		case "dashboard":
+			os.Exit(runDashboard(os.Args[2:]))
+		}
+	}
+
 	// Initialize application
-	app, err := app.New()
+	app, err := app.New(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "initialization failed: %v\n", err)
 		os.Exit(1)
 	}
 
-	app.Logger.Info("starting tct", "version", version.String(), "mode", app.Mode)
+	app.Logger.Info("starting tct", "version", version.String(), "mode", app.Mode, "seed", app.Config.Seed)
+	app.Logger.Info("resolved configuration", "settings", app.Settings())
+	if err := app.Snapshot(); err != nil {
+		app.Logger.Warn("configuration snapshot failed", "error", err)
+	}
+
+	opts := metrics.Options{
+		NativeHistograms: app.Config.NativeHistograms,
+		LabelLimit:       app.Config.MetricsLabelLimit,
+		LatencyBound:     app.Config.AssertP99,
+	}
+	expo := metrics.NewExposition(app.Config.MetricsNamespace, app.Config.MetricsLabels)
+	reg := metrics.NewRegistry(app.Config.GoMetrics, app.Config.ProcessMetrics)
+	metrics.RegisterInfo(reg, app.Mode, app.ExplicitSettings())
+
+	// Exit with a failure code only after the deferred final exports
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 
 	// Setup graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// Push metrics and traces to an OpenTelemetry collector if configured
+	if app.Config.OTelExporter == "otlp" {
+		tp, err := telemetry.New(ctx, expo.Exposed(reg), app.Config.OTelEndpoint, app.Config.OTelInterval, app.Config.OTelTraceRatio, app.Mode)
+		if err != nil {
+			app.Logger.Error("failed to start telemetry export", "error", err)
+			exitCode = 1
+			return
+		}
+		defer func() {
+			// Flush the final values after the context is cancelled
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tp.Shutdown(shutdownCtx); err != nil {
+				app.Logger.Warn("telemetry shutdown failed", "error", err)
+			}
+		}()
+	}
+
+	// Push metrics to a Pushgateway if configured, finally after shutdown
+	if app.Config.PushgatewayURL != nil {
+		pusher := metrics.NewPusher(expo.Exposed(reg), app.Config.PushgatewayURL, app.Config.PushJob, app.Mode)
+		go pusher.Run(ctx, app.Config.PushInterval, app.Logger)
+		defer func() {
+			pushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := pusher.Push(pushCtx); err != nil {
+				app.Logger.Warn("final metrics push failed", "error", err)
+			}
+		}()
+	}
+
+	// Emit metrics to a StatsD agent if configured, flushing after shutdown
+	if app.Config.StatsDAddr != "" {
+		sd, err := metrics.NewStatsD(reg, expo, app.Config.StatsDAddr, app.Config.StatsDFormat == "dogstatsd")
+		if err != nil {
+			app.Logger.Error("failed to start statsd emitter", "error", err)
+			exitCode = 1
+			return
+		}
+		opts.StatsD = sd
+		go sd.Run(ctx, app.Config.StatsDInterval, app.Logger)
+		defer func() {
+			if err := sd.Close(); err != nil {
+				app.Logger.Warn("final statsd flush failed", "error", err)
+			}
+		}()
+	}
+
 	// Run mode-specific logic
 	var runErr error
 	switch app.Mode {
 	case "sender":
-		runErr = runSender(ctx, app)
+		runErr = runSender(ctx, app, reg, expo, opts)
 	case "receiver":
-		runErr = runReceiver(ctx, app)
+		runErr = runReceiver(ctx, app, reg, expo, opts)
+	case "echo":
+		runErr = runEcho(ctx, app, reg, expo)
 	default:
 		fmt.Fprintf(os.Stderr, "invalid mode: %s\n", app.Mode)
-		os.Exit(1)
+		exitCode = 1
+		return
 	}
 
+	if errors.Is(runErr, errVerdictFailed) {
+		app.Logger.Error("verdict failed")
+		exitCode = exitVerdictFailed
+		return
+	}
 	if runErr != nil && runErr != context.Canceled {
 		app.Logger.Error("runtime error", "error", runErr)
-		os.Exit(1)
+		exitCode = 1
+		return
 	}
 
 	app.Logger.Info("shutdown complete")
 }
 
 // runSender starts the sender mode: HTTP server for observability + request generator.
-func runSender(ctx context.Context, app *app.App) error {
-	m := metrics.NewSenderMetrics()
+func runSender(ctx context.Context, app *app.App, reg *prometheus.Registry, expo *metrics.Exposition, opts metrics.Options) error {
+	m := metrics.NewSenderMetrics(reg, opts)
+	stats := metrics.NewSenderStats(reg)
+	if app.Config.StatsInterval > 0 {
+		go stats.Run(ctx, app.Config.StatsInterval, app.Logger)
+	}
 
 	// Start HTTP server for observability
-	srv := server.New(app.Config.SenderPort, app.Logger)
-	srv.RegisterCommonRoutes(handler.Healthz, handler.Readyz)
+	srv := server.New(app.Config.BindAddr, app.Config.SenderPort, app.Logger, serverTimeouts(app))
+	useMiddleware(app, srv)
+	if err := setServerTLS(app, srv); err != nil {
+		return err
+	}
+	srv.RegisterCommonRoutes(metrics.Handler(reg, expo), handler.Healthz, handler.Readyz)
+	srv.RegisterHandler("GET /config", handler.ConfigHandler(app.Settings))
+	srv.RegisterHandler("GET /stats", handler.StatsHandler(stats))
+	if app.Config.PprofEnabled {
+		srv.RegisterPprof()
+	}
+	heatmap := newHeatmap(ctx, app, stats)
+	if heatmap != nil {
+		srv.RegisterHandler("GET /heatmap", handler.HeatmapHandler(heatmap))
+	}
+	if ev := newEvents(ctx, app, m); ev != nil {
+		srv.RegisterHandler("GET /events", handler.EventsHandler(ev))
+	}
+
+	// Track the SLO if configured
+	objective, err := newSLO(app, reg)
+	if err != nil {
+		return err
+	}
+	srv.RegisterHandler("POST /control/metrics/reset", handler.MetricsResetHandler(func() {
+		m.Reset()
+		stats.Reset()
+		heatmap.Reset()
+		objective.Reset()
+	}))
 
 	// Run server in background
 	serverDone := make(chan error, 1)
@@ -69,10 +228,47 @@ func runSender(ctx context.Context, app *app.App) error {
 		serverDone <- srv.Start(ctx)
 	}()
 
+	// The configured request rate, replaced on reload
+	var configured atomic.Pointer[float64]
+	configured.Store(&app.Config.RPS)
+	base := func() float64 { return *configured.Load() }
+
+	// Take the request rate from the scenario if configured, otherwise
+	// from the rate schedule. A reloaded scenario starts over.
+	rate := generator.ScheduleRate(app.Config, m, base)
+	reloads := &reloads{m: m}
+	if src, err := app.Config.Document(app.Config.ScenarioFile, "scenario"); err != nil {
+		return err
+	} else if src != nil {
+		var runner atomic.Pointer[scenario.Runner]
+		runs := &restarter{ctx: ctx}
+		start := func(data []byte) error {
+			sc, err := scenario.Parse(data, src.Name())
+			if err != nil {
+				return err
+			}
+			r := scenario.NewRunner(sc, app.Logger, m)
+			runner.Store(r)
+			runs.start(r.Run)
+			return nil
+		}
+		if err := start(src.Data()); err != nil {
+			return err
+		}
+		rate = func() float64 { return runner.Load().RPS(base()) }
+		reloads.document(src, start)
+	}
+
+	// Apply the reloaded request rate; other settings take effect on
+	// restart
+	go reloads.watch(ctx, app, func(cfg *config.Config) {
+		configured.Store(&cfg.RPS)
+	})
+
 	// Run generator (blocks until context cancelled)
 	generatorDone := make(chan error, 1)
 	go func() {
-		generatorDone <- generator.Run(ctx, app.Config, app.Logger, m)
+		generatorDone <- generator.Run(ctx, app.Config, app.Logger, m, rate, objective)
 	}()
 
 	// Wait for either to complete
@@ -80,20 +276,544 @@ func runSender(ctx context.Context, app *app.App) error {
 	case err := <-serverDone:
 		return err
 	case err := <-generatorDone:
-		return err
+		if ctx.Err() == nil {
+			return err
+		}
 	case <-ctx.Done():
-		return ctx.Err()
+		// Judge the requests in flight at shutdown by their outcome
+		<-generatorDone
+	}
+	return judge(app, stats, stopped(ctx, serverDone, 1))
+}
+
+// judge evaluates the configured assertions on all requests of the run and
+// writes the verdict. Returns errVerdictFailed if the run failed, otherwise
+// err.
+func judge(app *app.App, stats *metrics.Stats, err error) error {
+	a := verdict.Assertions{
+		MaxErrorRate: app.Config.AssertErrorRate,
+		MaxP99:       app.Config.AssertP99,
+		NoResults:    app.Config.AssertNoResults,
+	}
+	if !a.Enabled() {
+		return err
+	}
+	sum, serr := stats.Total()
+	if serr != nil {
+		return serr
+	}
+	v := verdict.Evaluate(a, sum)
+	if werr := v.Write(app.Config.VerdictFile); werr != nil {
+		return werr
+	}
+	app.Logger.Info("verdict", "pass", v.Pass)
+	if !v.Pass {
+		return errVerdictFailed
 	}
+	return err
 }
 
 // runReceiver starts the receiver mode: HTTP server with /inbox endpoint.
-func runReceiver(ctx context.Context, app *app.App) error {
-	m := metrics.NewReceiverMetrics()
+func runReceiver(ctx context.Context, app *app.App, reg *prometheus.Registry, expo *metrics.Exposition, opts metrics.Options) error {
+	m := metrics.NewReceiverMetrics(reg, opts)
+	stats := metrics.NewReceiverStats(reg)
+	if app.Config.StatsInterval > 0 {
+		go stats.Run(ctx, app.Config.StatsInterval, app.Logger)
+	}
+
+	// Traffic servers: the receiver port first, then port personalities and
+	// the Unix socket
+	ports := app.Config.PortProfiles
+	traffic := []*server.Server{server.New(app.Config.BindAddr, app.Config.ReceiverPort, app.Logger, serverTimeouts(app))}
+	for _, port := range slices.Sorted(maps.Keys(ports)) {
+		traffic = append(traffic, server.New(app.Config.BindAddr, port, app.Logger, serverTimeouts(app)))
+	}
+	if app.Config.ReceiverSocket != "" {
+		traffic = append(traffic, server.NewUnix(app.Config.ReceiverSocket, app.Logger, serverTimeouts(app)))
+	}
+	useMiddleware(app, traffic...)
+	drain := server.NewDrain(app.Config.DrainDelay)
+	for _, srv := range traffic {
+		srv.SetMaxConnections(app.Config.MaxConnections)
+		srv.SetDrain(drain)
+	}
+	metrics.RegisterConnections(reg, "receiver", func() map[string]int {
+		states := map[string]int{}
+		for _, srv := range traffic {
+			cs := srv.ConnStats()
+			states["new"] += cs.New
+			states["active"] += cs.Active
+			states["idle"] += cs.Idle
+		}
+		return states
+	})
+	if app.Config.TLSEnabled {
+		mgr, err := certs.NewManager(app.Config, app.Logger, m)
+		if err != nil {
+			return err
+		}
+		for _, srv := range traffic {
+			srv.SetTLSConfig(mgr.TLSConfig())
+		}
+	} else if err := setServerTLS(app, traffic...); err != nil {
+		return err
+	}
+	if app.Config.HTTP2 {
+		for _, srv := range traffic {
+			srv.EnableHTTP2()
+		}
+	}
+	go handler.ManageKeepAlives(app.Config, app.Logger, m, func(enabled bool) {
+		for _, srv := range traffic {
+			srv.SetKeepAlivesEnabled(enabled)
+		}
+	})
+
+	// Observability and control endpoints (separate port if configured)
+	admin := traffic[0]
+	if app.Config.AdminPort > 0 {
+		host := app.Config.AdminBindAddr
+		if host == "" {
+			host = app.Config.BindAddr
+		}
+		admin = server.New(host, app.Config.AdminPort, app.Logger, serverTimeouts(app))
+		useMiddleware(app, admin)
+		if err := setServerTLS(app, admin); err != nil {
+			return err
+		}
+	}
+	admin.RegisterCommonRoutes(metrics.Handler(reg, expo), handler.Healthz, handler.DrainingReadyz(drain))
+	admin.RegisterHandler("GET /config", handler.ConfigHandler(app.Settings))
+	admin.RegisterHandler("GET /stats", handler.StatsHandler(stats))
+	if app.Config.PprofEnabled {
+		admin.RegisterPprof()
+	}
+	heatmap := newHeatmap(ctx, app, stats)
+	if heatmap != nil {
+		admin.RegisterHandler("GET /heatmap", handler.HeatmapHandler(heatmap))
+	}
+
+	// Experiment event log (disabled if size is 0)
+	ev := newEvents(ctx, app, m)
+	if ev != nil {
+		admin.RegisterHandler("GET /events", handler.EventsHandler(ev))
+	}
+
+	// Request inspection buffer (disabled if size is 0)
+	var buf *inspect.Buffer
+	if app.Config.InspectSize > 0 {
+		buf = inspect.NewBuffer(app.Config.InspectSize)
+		admin.RegisterHandler("GET /inspect/requests", handler.InspectHandler(buf))
+	}
+
+	// Access log (disabled if destination is empty)
+	var access *accesslog.Log
+	if app.Config.AccessLog != "" {
+		var err error
+		if access, err = accesslog.New(app.Config.AccessLog); err != nil {
+			return err
+		}
+		defer access.Close()
+	}
+
+	// Behavior layers applied on top of the configured base profile. The
+	// layers defined by documents are replaced when these are reloaded; a
+	// reloaded scenario starts over.
+	var layers []behavior.Layer
+	reloads := &reloads{m: m}
+	if src, err := app.Config.Document(app.Config.ScenarioFile, "scenario"); err != nil {
+		return err
+	} else if src != nil {
+		layer := &behavior.Swap{}
+		runs := &restarter{ctx: ctx}
+		start := func(data []byte) error {
+			sc, err := scenario.Parse(data, src.Name())
+			if err != nil {
+				return err
+			}
+			runner := scenario.NewRunner(sc, app.Logger, m)
+			layer.Set(runner)
+			runs.start(runner.Run)
+			return nil
+		}
+		if err := start(src.Data()); err != nil {
+			return err
+		}
+		layers = append(layers, layer)
+		reloads.document(src, start)
+	}
+	if src, err := app.Config.Document(app.Config.ScheduleFile, "schedule"); err != nil {
+		return err
+	} else if src != nil {
+		layer := &behavior.Swap{}
+		runs := &restarter{ctx: ctx}
+		start := func(data []byte) error {
+			sched, err := schedule.Parse(data, src.Name())
+			if err != nil {
+				return err
+			}
+			sl := schedule.NewLayer(sched, app.Logger, m)
+			layer.Set(sl)
+			runs.start(sl.Run)
+			return nil
+		}
+		if err := start(src.Data()); err != nil {
+			return err
+		}
+		layers = append(layers, layer)
+		reloads.document(src, start)
+	}
+	if src, err := app.Config.Document(app.Config.ProfilesFile, "profiles"); err != nil {
+		return err
+	} else if src != nil {
+		portLayer, headerLayer := &behavior.Swap{}, &behavior.Swap{}
+		apply := func(data []byte) error {
+			profs, err := profiles.Parse(data, src.Name())
+			if err != nil {
+				return err
+			}
+			if len(ports) > 0 {
+				pl, err := profiles.NewPortLayer(ports, profs)
+				if err != nil {
+					return err
+				}
+				portLayer.Set(pl)
+			}
+			headerLayer.Set(profiles.NewLayer(app.Config.ProfileHeader, profs, m))
+			return nil
+		}
+		if err := apply(src.Data()); err != nil {
+			return err
+		}
+		if len(ports) > 0 {
+			layers = append(layers, portLayer)
+		}
+		layers = append(layers, headerLayer)
+		reloads.document(src, apply)
+	}
+	if app.Config.BrownoutConnRate > 0 {
+		bo := brownout.NewLayer(app.Config.BrownoutConnRate, random.New(app.Config.Seed, "brownout"), m)
+		layers = append(layers, bo)
+		for _, srv := range traffic {
+			srv.SetConnContext(bo.ConnContext)
+		}
+	}
+	res := behavior.NewResolver(behavior.FromConfig(app.Config), layers...)
+
+	objective, err := newSLO(app, reg)
+	if err != nil {
+		return err
+	}
+	rec := handler.NewRecorder(m, buf, access, objective, app.Config.ServerTiming)
+	errs, err := errbody.New(app.Config.ErrorBody, app.Config.ErrorBodyTemplate)
+	if err != nil {
+		return err
+	}
+	var sizes atomic.Pointer[bodysize.Rules]
+	sizes.Store(&bodysize.Rules{})
+	if src, err := app.Config.Document(app.Config.SizeRulesFile, "size_rules"); err != nil {
+		return err
+	} else if src != nil {
+		apply := func(data []byte) error {
+			rules, err := bodysize.Parse(data, src.Name())
+			if err != nil {
+				return err
+			}
+			sizes.Store(&rules)
+			return nil
+		}
+		if err := apply(src.Data()); err != nil {
+			return err
+		}
+		reloads.document(src, apply)
+	}
+
+	// Apply reloaded behavior parameters to the base profile; other
+	// settings take effect on restart
+	go reloads.watch(ctx, app, func(cfg *config.Config) {
+		res.SetBase(behavior.FromConfig(cfg))
+	})
+	onOutage := func(active bool) {
+		ev.Phase("outage", "outage", active)
+		m.SetOutageState(active)
+		if app.Config.Outage.Mode == "refuse" {
+			for _, srv := range traffic {
+				srv.SetAccepting(!active)
+			}
+		}
+	}
+	outage := handler.NewOutage(app.Config, app.Logger, onOutage)
+	hangs := handler.NewHangs(ctx, app.Logger, m)
+	inbox := handler.InboxHandler(app.Config, app.Logger, m, rec, res, outage, hangs, errs, func() bodysize.Rules { return *sizes.Load() }, ev)
+	redirect := handler.RedirectHandler(app.Config, app.Logger, rec)
+	mws := []server.Middleware{drain.Reject, server.RequestID, telemetry.Handler, handler.Inflight(m), handler.Recover(app.Logger, m, app.Config.PanicRecover)}
+	for _, srv := range traffic {
+		srv.RegisterHandler("POST /inbox", inbox, mws...)
+		if app.Config.CacheControl != "" || app.Config.CacheETag || app.Config.CacheLastModified {
+			// Conditional requests only apply to GET and HEAD
+			srv.RegisterHandler("GET /inbox", inbox, mws...)
+		}
+		srv.RegisterHandler("/redirect/{hop}", redirect, mws...)
+	}
+	admin.RegisterHandler("POST /control/outage", handler.OutageControlHandler(outage))
+	admin.RegisterHandler("POST /control/hangs/flush", handler.FlushHangsHandler(hangs))
+	admin.RegisterHandler("POST /control/metrics/reset", handler.MetricsResetHandler(func() {
+		m.Reset()
+		stats.Reset()
+		heatmap.Reset()
+		objective.Reset()
+	}))
 
-	// Start HTTP server
-	srv := server.New(app.Config.ReceiverPort, app.Logger)
-	srv.RegisterCommonRoutes(handler.Healthz, handler.Readyz)
-	srv.RegisterHandler("POST /inbox", handler.InboxHandler(app.Config, app.Logger, m))
+	if admin == traffic[0] {
+		return serve(ctx, traffic...)
+	}
+	return serveWithAdmin(ctx, admin, traffic)
+}
+
+// newSLO creates an SLO tracker exporting burn rates to reg, or returns nil
+// if no SLO is configured.
+func newSLO(app *app.App, reg *prometheus.Registry) (*slo.Tracker, error) {
+	if app.Config.SLOObjective == 0 {
+		return nil, nil
+	}
+	windows := app.Config.SLOWindows
+	t := slo.New(app.Config.SLOObjective, app.Config.SLOLatency, slices.Max(windows))
+	if err := metrics.RegisterBurnRates(reg, app.Mode, windows, t.BurnRate); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
 
-	return srv.Start(ctx)
+// newHeatmap creates the latency heatmap of stats and starts recording, or
+// returns nil if it is disabled.
+func newHeatmap(ctx context.Context, app *app.App, stats *metrics.Stats) *metrics.Heatmap {
+	if app.Config.HeatmapSlices == 0 {
+		return nil
+	}
+	heatmap := metrics.NewHeatmap(stats, app.Config.HeatmapSlice, app.Config.HeatmapSlices)
+	go heatmap.Run(ctx)
+	return heatmap
+}
+
+// phaseSource is a metric set reporting experiment phase updates.
+type phaseSource interface {
+	NotifyPhases(fn func(source, phase string, active bool))
+}
+
+// newEvents creates the experiment event log recording the phase
+// transitions of phases and starts its webhook delivery, or returns nil if
+// it is disabled.
+func newEvents(ctx context.Context, app *app.App, phases phaseSource) *events.Log {
+	if app.Config.EventsSize == 0 {
+		return nil
+	}
+	ev := events.New(app.Config.EventsSize, app.Config.EventsWebhook, app.Logger)
+	phases.NotifyPhases(ev.Phase)
+	go ev.Run(ctx)
+	return ev
+}
+
+// reloadRecorder is a metric set counting configuration reloads.
+type reloadRecorder interface {
+	RecordReload(result string)
+}
+
+// reloads applies reloaded configurations and documents, counting the
+// results in m.
+type reloads struct {
+	m    reloadRecorder
+	docs []app.Document
+}
+
+// document adds a document to refresh along with the configuration; apply
+// applies its changed content.
+func (r *reloads) document(src *config.Source, apply func(data []byte) error) {
+	r.docs = append(r.docs, app.Document{Source: src, Apply: func(data []byte) error {
+		err := apply(data)
+		r.record(err)
+		return err
+	}})
+}
+
+// watch reloads the configuration and documents until ctx is cancelled
+// (see app.App.Watch), passing reloaded configurations to apply.
+func (r *reloads) watch(ctx context.Context, a *app.App, apply func(cfg *config.Config)) {
+	a.Watch(ctx, func(cfg *config.Config, err error) {
+		r.record(err)
+		if err == nil {
+			apply(cfg)
+			a.Logger.Info("configuration reloaded")
+		}
+	}, r.docs...)
+}
+
+// record counts a reload by its result.
+func (r *reloads) record(err error) {
+	if err != nil {
+		r.m.RecordReload("error")
+	} else {
+		r.m.RecordReload("ok")
+	}
+}
+
+// restarter runs one task at a time in the background until ctx is
+// cancelled. Starting a task stops the previous one and waits for it to
+// return, e.g. the runner of a reloaded scenario.
+type restarter struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// start stops the running task, if any, and runs run.
+func (r *restarter) start(run func(ctx context.Context) error) {
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	done := make(chan struct{})
+	r.cancel, r.done = cancel, done
+	go func() {
+		defer close(done)
+		run(ctx)
+	}()
+}
+
+// serverTimeouts returns the configured HTTP server timeouts.
+func serverTimeouts(app *app.App) server.Timeouts {
+	return server.Timeouts{
+		Read:       app.Config.ServerReadTimeout,
+		ReadHeader: app.Config.ServerReadHeaderTimeout,
+		Write:      app.Config.ServerWriteTimeout,
+		Idle:       app.Config.ServerIdleTimeout,
+		Shutdown:   app.Config.DrainTimeout,
+	}
+}
+
+// useMiddleware adds the configured middleware of all servers.
+func useMiddleware(app *app.App, servers ...*server.Server) {
+	if app.Config.HTTPLogSample > 0 {
+		accessLog := server.AccessLog(app.Logger, app.Config.HTTPLogSample, random.New(app.Config.Seed, "http_log"))
+		for _, srv := range servers {
+			srv.Use(accessLog)
+		}
+	}
+}
+
+// setServerTLS makes the servers serve HTTPS with the configured server key
+// pair. No-op if none is configured.
+func setServerTLS(app *app.App, servers ...*server.Server) error {
+	if app.Config.ServerTLSCert == "" {
+		return nil
+	}
+	tlsConfig, err := certs.ServerTLSConfig(app.Config.ServerTLSCert, app.Config.ServerTLSKey)
+	if err != nil {
+		return err
+	}
+	for _, srv := range servers {
+		srv.SetTLSConfig(tlsConfig)
+	}
+	return nil
+}
+
+// serve runs the servers in background until one of them stops or the
+// context is cancelled. After cancellation it waits for all servers to stop.
+func serve(ctx context.Context, servers ...*server.Server) error {
+	done := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			done <- srv.Start(ctx)
+		}()
+	}
+
+	// Wait for any to complete
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return stopped(ctx, done, len(servers))
+	}
+}
+
+// stopped waits for n servers started with ctx to complete their graceful
+// shutdown after ctx was cancelled. Returns their shutdown errors, if any,
+// otherwise ctx.Err().
+func stopped(ctx context.Context, done <-chan error, n int) error {
+	var errs []error
+	for range n {
+		errs = append(errs, <-done)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// serveWithAdmin runs the traffic servers and the admin server with
+// independent lifecycles: the admin server is stopped only after the traffic
+// servers, so metrics, health, and control endpoints stay reachable while
+// traffic drains.
+func serveWithAdmin(ctx context.Context, admin *server.Server, traffic []*server.Server) error {
+	adminCtx, stopAdmin := context.WithCancel(context.Background())
+	defer stopAdmin()
+	adminDone := make(chan error, 1)
+	go func() {
+		adminDone <- admin.Start(adminCtx)
+	}()
+	trafficDone := make(chan error, 1)
+	go func() {
+		trafficDone <- serve(ctx, traffic...)
+	}()
+
+	select {
+	case err := <-adminDone:
+		return err
+	case err := <-trafficDone:
+		stopAdmin()
+		<-adminDone
+		return err
+	}
+}
+
+// runEcho starts the echo mode: HTTP server for observability + L4 echo listener.
+func runEcho(ctx context.Context, app *app.App, reg *prometheus.Registry, expo *metrics.Exposition) error {
+	m := metrics.NewEchoMetrics(reg)
+
+	// Nothing is reloaded in echo mode, so SIGHUP must not terminate it
+	signal.Ignore(syscall.SIGHUP)
+
+	// Start HTTP server for observability
+	srv := server.New(app.Config.BindAddr, app.Config.ReceiverPort, app.Logger, serverTimeouts(app))
+	useMiddleware(app, srv)
+	if err := setServerTLS(app, srv); err != nil {
+		return err
+	}
+	srv.RegisterCommonRoutes(metrics.Handler(reg, expo), handler.Healthz, handler.Readyz)
+	srv.RegisterHandler("GET /config", handler.ConfigHandler(app.Settings))
+	if app.Config.PprofEnabled {
+		srv.RegisterPprof()
+	}
+
+	// Run server in background
+	serverDone := make(chan error, 1)
+	go func() {
+		serverDone <- srv.Start(ctx)
+	}()
+
+	// Run echo listener (blocks until context cancelled)
+	echoDone := make(chan error, 1)
+	go func() {
+		echoDone <- echo.Run(ctx, app.Config, app.Logger, m)
+	}()
+
+	// Wait for either to complete
+	select {
+	case err := <-serverDone:
+		return err
+	case err := <-echoDone:
+		return err
+	case <-ctx.Done():
+		return stopped(ctx, serverDone, 1)
+	}
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// sparkTicks are the bar characters of the rate sparkline, lowest first.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// runTop renders live request stats of a running sender or receiver in the
+// terminal until interrupted. Returns the process exit code.
+func runTop(args []string) int {
+	fs := flag.NewFlagSet("top", flag.ContinueOnError)
+	url := fs.String("url", "http://localhost:9090/metrics", "metrics endpoint of the sender or receiver")
+	interval := fs.Duration("interval", time.Second, "refresh interval")
+	namespace := fs.String("namespace", "tct", "metric name prefix of the instance (TCT_METRICS_NAMESPACE)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "invalid interval (must be positive)")
+		return 1
+	}
+
+	g := metrics.NewRemoteGatherer(*url, *namespace)
+	mode, err := g.Mode()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	stats := metrics.NewReceiverStats(g)
+	if mode == "sender" {
+		stats = metrics.NewSenderStats(g)
+	}
+	stats.Window()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	var rates []float64
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+
+		sum, err := stats.Window()
+		if err != nil {
+			fmt.Printf("\x1b[H\x1b[2Jtct top  %s\n\n%v\n", *url, err)
+			continue
+		}
+		rates = append(rates, sum.RPS)
+		if len(rates) > 60 {
+			rates = rates[1:]
+		}
+		total, _ := stats.Total()
+		fmt.Print("\x1b[H\x1b[2J" + renderTop(*url, mode, sum, total, rates))
+	}
+}
+
+// renderTop formats the dashboard for the last window and the totals.
+func renderTop(url, mode string, sum, total metrics.Summary, rates []float64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tct top  %s (%s)\n\n", url, mode)
+	fmt.Fprintf(&b, "rate      %8.2f req/s   %s\n", sum.RPS, sparkline(rates))
+	fmt.Fprintf(&b, "errors    %8.2f %%       %s\n", errorPercent(sum), errorClasses(sum))
+	fmt.Fprintf(&b, "latency   p50 %.2fms  p90 %.2fms  p99 %s\n\n", sum.P50Ms, sum.P90Ms, p99(sum))
+	fmt.Fprintf(&b, "total     %.0f requests, %.2f %% errors, p99 %s\n", total.Requests, errorPercent(total), p99(total))
+	return b.String()
+}
+
+// p99 formats the p99 latency, marking a p99 above the largest bucket.
+func p99(sum metrics.Summary) string {
+	if sum.P99Overflow {
+		return fmt.Sprintf(">%.2fms", sum.P99Ms)
+	}
+	return fmt.Sprintf("%.2fms", sum.P99Ms)
+}
+
+// errorPercent returns the percentage of non-ok results.
+func errorPercent(sum metrics.Summary) float64 {
+	if sum.Requests == 0 {
+		return 0
+	}
+	return (sum.Requests - sum.Results["ok"]) / sum.Requests * 100
+}
+
+// errorClasses formats the counts of non-ok results by class.
+func errorClasses(sum metrics.Summary) string {
+	var classes []string
+	for _, result := range slices.Sorted(maps.Keys(sum.Results)) {
+		if result != "ok" {
+			classes = append(classes, fmt.Sprintf("%s=%.0f", result, sum.Results[result]))
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+// sparkline renders values as bars scaled to the largest value.
+func sparkline(values []float64) string {
+	top := 0.0
+	for _, v := range values {
+		top = max(top, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if top > 0 {
+			i = int(v / top * float64(len(sparkTicks)-1))
+		}
+		b.WriteRune(sparkTicks[i])
+	}
+	return b.String()
+}
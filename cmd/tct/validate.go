@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/neox5/tct/internal/app"
+	"github.com/neox5/tct/internal/bodysize"
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/errbody"
+	"github.com/neox5/tct/internal/profiles"
+	"github.com/neox5/tct/internal/scenario"
+	"github.com/neox5/tct/internal/schedule"
+)
+
+// runValidate loads and validates the configuration from args, the
+// environment, and the config file, including all referenced documents,
+// and prints a report. Returns the process exit code (0 if valid).
+func runValidate(args []string) int {
+	cfg, err := app.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Printf("config: invalid\n%v\n", err)
+		return 1
+	}
+	source := "environment"
+	if cfg.File != nil {
+		source = cfg.File.Path()
+	}
+	fmt.Printf("config: ok (mode %s, %s)\n", cfg.Mode, source)
+	if cfg.StrictEnv == "warn" {
+		for _, key := range app.UnknownEnv(cfg) {
+			fmt.Printf("warning: unknown environment variable %s\n", key)
+		}
+	}
+
+	// Documents and templates are parsed at startup
+	failed := false
+	for _, check := range documentChecks(cfg) {
+		switch err := check.run(); {
+		case errors.Is(err, errNotConfigured):
+			continue
+		case err != nil:
+			fmt.Printf("%s: invalid\n%v\n", check.name, err)
+			failed = true
+		default:
+			fmt.Printf("%s: ok\n", check.name)
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// errNotConfigured marks checks of optional settings that are not set.
+var errNotConfigured = errors.New("not configured")
+
+// validateCheck validates one document or template of the configuration.
+type validateCheck struct {
+	name string
+	run  func() error
+}
+
+// documentChecks returns the checks of documents and templates loaded at
+// startup in the configured mode.
+func documentChecks(cfg *config.Config) []validateCheck {
+	// document parses the file or config file section with parse
+	document := func(path, section string, parse func(data []byte, source string) error) func() error {
+		return func() error {
+			src, err := cfg.Document(path, section)
+			if err != nil {
+				return err
+			}
+			if src == nil {
+				return errNotConfigured
+			}
+			return parse(src.Data(), src.Name())
+		}
+	}
+
+	scenarioCheck := validateCheck{"scenario", document(cfg.ScenarioFile, "scenario", func(data []byte, source string) error {
+		_, err := scenario.Parse(data, source)
+		return err
+	})}
+	switch cfg.Mode {
+	case "sender":
+		return []validateCheck{scenarioCheck}
+	case "receiver":
+	default:
+		return nil
+	}
+
+	return []validateCheck{
+		scenarioCheck,
+		{"schedule", document(cfg.ScheduleFile, "schedule", func(data []byte, source string) error {
+			_, err := schedule.Parse(data, source)
+			return err
+		})},
+		{"profiles", document(cfg.ProfilesFile, "profiles", func(data []byte, source string) error {
+			profs, err := profiles.Parse(data, source)
+			if err != nil {
+				return err
+			}
+			_, err = profiles.NewPortLayer(cfg.PortProfiles, profs)
+			return err
+		})},
+		{"size rules", document(cfg.SizeRulesFile, "size_rules", func(data []byte, source string) error {
+			_, err := bodysize.Parse(data, source)
+			return err
+		})},
+		{"error body", func() error {
+			_, err := errbody.New(cfg.ErrorBody, cfg.ErrorBodyTemplate)
+			return err
+		}},
+	}
+}
@@ -0,0 +1,63 @@
+// Package accesslog provides a structured NDJSON access log for the receiver.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is a single access log line.
+type Entry struct {
+	Time      time.Time `json:"ts"`
+	Client    string    `json:"client"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Size      int64     `json:"size"`
+	LatencyMs float64   `json:"latency_ms"`
+	DelayMs   float64   `json:"delay_ms"`
+	Outcome   string    `json:"outcome"`
+	Fault     string    `json:"fault,omitempty"`
+}
+
+// Log writes entries as newline-delimited JSON.
+// It is safe for concurrent use.
+type Log struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	out io.WriteCloser // nil for stdout
+}
+
+// New creates an access log writing to dest.
+// dest is either "stdout" or a file path (appended to, created if missing).
+func New(dest string) (*Log, error) {
+	if dest == "stdout" {
+		return &Log{enc: json.NewEncoder(os.Stdout)}, nil
+	}
+
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+
+	return &Log{enc: json.NewEncoder(f), out: f}, nil
+}
+
+// Write appends an entry to the log. Write errors are ignored.
+func (l *Log) Write(e Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enc.Encode(e)
+}
+
+// Close closes the underlying file, if any.
+func (l *Log) Close() error {
+	if l.out == nil {
+		return nil
+	}
+	return l.out.Close()
+}
@@ -2,7 +2,12 @@
 package app
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/neox5/tct/internal/config"
 	"github.com/neox5/tct/internal/env"
@@ -14,20 +19,29 @@ type App struct {
 	Mode   string
 	Config *config.Config
 	Logger *logger.Logger
+	args   []string
+	lookup env.Lookup
+
+	// reloaded is the last configuration accepted by Watch, nil until the
+	// first successful reload.
+	reloaded atomic.Pointer[config.Config]
 }
 
 // New initializes the application by loading configuration and setting up logging.
 // It validates the mode and returns an error if initialization fails.
-func New() (*App, error) {
-	// Load configuration from environment
-	cfg := &config.Config{}
-	if err := env.Parse(cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+// args are the command-line arguments without the program name. Every
+// setting has a flag named after its environment variable (--error-rate for
+// TCT_ERROR_RATE) and a config file may be given with --config or
+// TCT_CONFIG_FILE. Precedence is flags > environment > config file > defaults.
+func New(args []string) (*App, error) {
+	cfg, lookup, err := load(args, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate mode
-	if cfg.Mode != "sender" && cfg.Mode != "receiver" {
-		return nil, fmt.Errorf("invalid mode %q (must be 'sender' or 'receiver')", cfg.Mode)
+	// Pick a seed so the run can be reproduced from the startup log
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
 	}
 
 	// Initialize logger
@@ -36,9 +50,187 @@ func New() (*App, error) {
 		return nil, fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
+	// Warn about renamed variables set by their old names
+	for alias, key := range env.Aliases(cfg) {
+		if _, ok := lookup(alias); ok {
+			log.Warn("deprecated variable name", "name", alias, "replacement", key)
+		}
+	}
+
+	// Warn about variables that do not configure anything (likely typos)
+	if cfg.StrictEnv == "warn" {
+		for _, key := range UnknownEnv(cfg) {
+			log.Warn("ignoring unknown environment variable", "name", key)
+		}
+	}
+
 	return &App{
 		Mode:   cfg.Mode,
 		Config: cfg,
 		Logger: log,
+		args:   args,
+		lookup: lookup,
 	}, nil
 }
+
+// Load parses and validates the configuration from args, the environment,
+// and the config file without initializing the application, e.g. to check
+// a configuration before rolling it out.
+func Load(args []string) (*config.Config, error) {
+	cfg, _, err := load(args, nil)
+	return cfg, err
+}
+
+// load parses and validates the configuration from args, the environment,
+// and the config file, parsed from src if it is not nil (e.g. as refreshed
+// by Watch) instead of read again. The returned lookup yields the raw
+// values of all sources in order of precedence.
+func load(args []string, src *config.Source) (*config.Config, env.Lookup, error) {
+	cfg := &config.Config{}
+
+	// Parse flags (config file flag takes precedence over environment)
+	fs := flag.NewFlagSet("tct", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("TCT_CONFIG_FILE"), "path to YAML or JSON config file (TCT_CONFIG_FILE)")
+	flags := env.Flags(fs, cfg, "TCT_")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	// Load configuration from flags, environment, config file, then defaults
+	lookups := []env.Lookup{flags, os.LookupEnv}
+	if *configFile != "" {
+		var f *config.File
+		var err error
+		if src != nil {
+			f, err = config.LoadSource(src, env.Keys(cfg))
+		} else {
+			f, err = config.LoadFile(*configFile, env.Keys(cfg))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.File = f
+		lookups = append(lookups, f.Lookup)
+	}
+	lookup := env.Chain(lookups...)
+	if err := env.ParseWith(cfg, lookup); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Reject variables that do not configure anything (likely typos)
+	if cfg.StrictEnv == "error" {
+		if unknown := UnknownEnv(cfg); len(unknown) > 0 {
+			return nil, nil, fmt.Errorf("unknown environment variables %v (set TCT_STRICT_ENV=warn to ignore)", unknown)
+		}
+	}
+
+	// Reject settings of other modes, which would silently have no effect
+	if err := checkMode(cfg.Mode, lookup); err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, lookup, nil
+}
+
+// sections lists the configuration sections used only by one mode.
+var sections = []struct {
+	mode    string
+	section any
+}{
+	{"sender", &config.SenderConfig{}},
+	{"receiver", &config.ReceiverConfig{}},
+	{"echo", &config.EchoConfig{}},
+}
+
+// checkMode returns an error for every setting of a mode other than mode
+// that is set through lookup.
+func checkMode(mode string, lookup env.Lookup) error {
+	var errs []error
+	for _, s := range sections {
+		if s.mode == mode {
+			continue
+		}
+		for _, key := range env.Keys(s.section) {
+			if _, ok := lookup(key); ok {
+				errs = append(errs, fmt.Errorf("%s is only used in %s mode (mode is %s)", key, s.mode, mode))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Setting documents a configuration setting and the mode using it ("all"
+// for common settings).
+type Setting struct {
+	env.Field
+	Mode string `json:"mode"`
+}
+
+// Describe returns documentation of all settings, common settings first,
+// then the settings of each mode.
+func Describe() []Setting {
+	modes := map[string]string{}
+	for _, s := range sections {
+		for _, key := range env.Keys(s.section) {
+			modes[key] = s.mode
+		}
+	}
+
+	var settings []Setting
+	for _, field := range env.Describe(&config.Config{}) {
+		mode := modes[field.Key]
+		if mode == "" {
+			mode = "all"
+		}
+		settings = append(settings, Setting{Field: field, Mode: mode})
+	}
+	return settings
+}
+
+// Settings returns the resolved settings of the common and active mode
+// sections keyed by environment variable, with secrets redacted. After a
+// reload (see Watch) they are those of the reloaded configuration.
+func (a *App) Settings() map[string]string {
+	cfg := a.Config
+	if reloaded := a.reloaded.Load(); reloaded != nil {
+		cfg = reloaded
+	}
+	settings := env.Values(cfg)
+	for _, s := range sections {
+		if s.mode == a.Mode {
+			continue
+		}
+		for _, key := range env.Keys(s.section) {
+			delete(settings, key)
+		}
+	}
+	return settings
+}
+
+// ExplicitSettings returns the settings of Settings that are set explicitly
+// by flag, environment variable (or a deprecated alias), or config file
+// rather than left at their defaults.
+func (a *App) ExplicitSettings() map[string]string {
+	set := map[string]bool{}
+	for alias, key := range env.Aliases(a.Config) {
+		if _, ok := a.lookup(alias); ok {
+			set[key] = true
+		}
+	}
+
+	settings := a.Settings()
+	for key := range settings {
+		if _, ok := a.lookup(key); !ok && !set[key] {
+			delete(settings, key)
+		}
+	}
+	return settings
+}
+
+// UnknownEnv returns the TCT_ environment variables not bound to any setting.
+func UnknownEnv(cfg *config.Config) []string {
+	return env.Unknown(cfg, "TCT_", "TCT_CONFIG_FILE")
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+)
+
+// Reload loads and validates the configuration again from the original
+// arguments, the environment, and the config file. The resolved seed of the
+// running configuration is kept.
+func (a *App) Reload() (*config.Config, error) {
+	return a.reload(nil)
+}
+
+// reload is Reload with the config file parsed from src if it is not nil.
+func (a *App) reload(src *config.Source) (*config.Config, error) {
+	cfg, _, err := load(a.args, src)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Mode != a.Mode {
+		return nil, fmt.Errorf("mode cannot change on reload (running %q, got %q)", a.Mode, cfg.Mode)
+	}
+	cfg.Seed = a.Config.Seed
+	return cfg, nil
+}
+
+// Document is a document (e.g. a scenario) that Watch refreshes along with
+// the configuration.
+type Document struct {
+	Source *config.Source
+	// Apply applies the changed content; on error the running content
+	// stays in effect.
+	Apply func(data []byte) error
+}
+
+// Watch reloads the configuration on SIGHUP and, if the config watch
+// interval is non-zero, whenever the config file changes (see
+// config.Source.Refresh). The documents are refreshed the same way and
+// applied when they change; documents embedded in the config file are
+// not. apply is called with the new configuration or the reload error; on
+// error the running configuration stays in effect. Settings reports the
+// last accepted configuration. Blocks until ctx is cancelled.
+func (a *App) Watch(ctx context.Context, apply func(cfg *config.Config, err error), docs ...Document) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// Poll the config file and documents only if configured
+	var tick <-chan time.Time
+	var src *config.Source
+	if a.Config.File != nil {
+		src = a.Config.File.Source()
+	}
+	if (src != nil || len(docs) > 0) && a.Config.ConfigWatchInterval > 0 {
+		ticker := time.NewTicker(a.Config.ConfigWatchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// Reload from the refreshed config file, or read it again if nil
+	reload := func(from *config.Source) {
+		cfg, err := a.reload(from)
+		if err != nil {
+			a.Logger.Error("configuration reload rejected", "error", err)
+		} else {
+			a.reloaded.Store(cfg)
+			if cfg.File != nil {
+				src = cfg.File.Source()
+			}
+		}
+		apply(cfg, err)
+	}
+
+	for {
+		select {
+		case <-hup:
+			a.Logger.Info("reloading configuration", "trigger", "sighup")
+			reload(nil)
+			a.refresh(docs)
+		case <-tick:
+			a.refresh(docs)
+			if src == nil {
+				continue
+			}
+			changed, err := src.Refresh()
+			if err != nil {
+				a.Logger.Warn("failed to check config file", "error", err)
+			}
+			if changed {
+				a.Logger.Info("reloading configuration", "trigger", "file_change")
+				reload(src)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh applies the documents that changed since the last refresh.
+func (a *App) refresh(docs []Document) {
+	for _, doc := range docs {
+		changed, err := doc.Source.Refresh()
+		if err != nil {
+			a.Logger.Warn("failed to check document", "source", doc.Source.Name(), "error", err)
+		}
+		if !changed {
+			continue
+		}
+		if err := doc.Apply(doc.Source.Data()); err != nil {
+			a.Logger.Error("document reload rejected", "source", doc.Source.Name(), "error", err)
+			continue
+		}
+		a.Logger.Info("document reloaded", "source", doc.Source.Name())
+	}
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// snapshot is the explicitly set configuration of a run persisted in the
+// state file.
+type snapshot struct {
+	Time     time.Time         `json:"time"`
+	Settings map[string]string `json:"settings"`
+}
+
+// Snapshot compares the explicitly set configuration (see ExplicitSettings)
+// with the one persisted by the previous run, logs every changed setting,
+// and persists the current one. Defaults and derived values such as a
+// generated seed are left out, so only deliberate changes are reported.
+// No-op if no state file is configured. A missing or unreadable previous
+// snapshot is not an error.
+func (a *App) Snapshot() error {
+	path := a.Config.StateFile
+	if path == "" {
+		return nil
+	}
+
+	current := snapshot{Time: time.Now(), Settings: a.ExplicitSettings()}
+	if data, err := os.ReadFile(path); err == nil {
+		var prev snapshot
+		if err := json.Unmarshal(data, &prev); err != nil {
+			a.Logger.Warn("ignoring unreadable configuration snapshot", "path", path, "error", err)
+		} else {
+			a.logDiff(prev, current)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		a.Logger.Warn("ignoring unreadable configuration snapshot", "path", path, "error", err)
+	}
+
+	// Write atomically so a crash never leaves a partial snapshot
+	data, err := json.MarshalIndent(current, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration snapshot: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write configuration snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write configuration snapshot: %w", err)
+	}
+	return nil
+}
+
+// logDiff logs the settings that differ between the previous and current
+// snapshot.
+func (a *App) logDiff(prev, current snapshot) {
+	keys := slices.Sorted(maps.Keys(prev.Settings))
+	for key := range current.Settings {
+		if _, ok := prev.Settings[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	changed := 0
+	for _, key := range keys {
+		old, hadOld := prev.Settings[key]
+		cur, hasCur := current.Settings[key]
+		if hadOld == hasCur && old == cur {
+			continue
+		}
+		changed++
+		a.Logger.Info("configuration changed since previous run", "name", key, "previous", old, "current", cur)
+	}
+	if changed == 0 {
+		a.Logger.Info("configuration unchanged since previous run", "previous_start", prev.Time)
+	}
+}
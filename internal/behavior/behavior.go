@@ -0,0 +1,195 @@
+// Package behavior defines the fault-injection profile applied to receiver
+// requests and the layering used to derive it at runtime.
+package behavior
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+)
+
+// Profile holds the fault-injection parameters applied to a request.
+type Profile struct {
+	ResponseDelay  time.Duration
+	ResponseJitter time.Duration
+	HangRate       float64
+	ErrorRate      float64
+	BadRequestRate float64
+	SlowReadRate   float64
+	RedirectRate   float64
+	ResetRate      float64
+	PanicRate      float64
+	GoawayRate     float64
+	ConnCloseRate  float64
+	Outage         bool
+}
+
+// FromConfig returns the base profile defined by the configuration.
+func FromConfig(cfg *config.Config) Profile {
+	return Profile{
+		ResponseDelay:  cfg.ResponseDelay,
+		ResponseJitter: cfg.ResponseJitter,
+		HangRate:       cfg.HangRate,
+		ErrorRate:      cfg.ErrorRate,
+		BadRequestRate: cfg.BadRequestRate,
+		SlowReadRate:   cfg.SlowReadRate,
+		RedirectRate:   cfg.RedirectRate,
+		ResetRate:      cfg.StreamResetRate,
+		PanicRate:      cfg.PanicRate,
+		GoawayRate:     cfg.GoawayRate,
+		ConnCloseRate:  cfg.ConnCloseRate,
+	}
+}
+
+// Override holds optional profile changes. Nil fields keep the base value.
+type Override struct {
+	ResponseDelay  *time.Duration `yaml:"response_delay"`
+	ResponseJitter *time.Duration `yaml:"response_jitter"`
+	HangRate       *float64       `yaml:"hang_rate"`
+	ErrorRate      *float64       `yaml:"error_rate"`
+	BadRequestRate *float64       `yaml:"bad_request_rate"`
+	SlowReadRate   *float64       `yaml:"slow_read_rate"`
+	RedirectRate   *float64       `yaml:"redirect_rate"`
+	ResetRate      *float64       `yaml:"stream_reset_rate"`
+	PanicRate      *float64       `yaml:"panic_rate"`
+	GoawayRate     *float64       `yaml:"goaway_rate"`
+	ConnCloseRate  *float64       `yaml:"conn_close_rate"`
+	Outage         *bool          `yaml:"outage"`
+}
+
+// Apply returns p with all non-nil override fields applied.
+func (o Override) Apply(p Profile) Profile {
+	if o.ResponseDelay != nil {
+		p.ResponseDelay = *o.ResponseDelay
+	}
+	if o.ResponseJitter != nil {
+		p.ResponseJitter = *o.ResponseJitter
+	}
+	if o.HangRate != nil {
+		p.HangRate = *o.HangRate
+	}
+	if o.ErrorRate != nil {
+		p.ErrorRate = *o.ErrorRate
+	}
+	if o.BadRequestRate != nil {
+		p.BadRequestRate = *o.BadRequestRate
+	}
+	if o.SlowReadRate != nil {
+		p.SlowReadRate = *o.SlowReadRate
+	}
+	if o.RedirectRate != nil {
+		p.RedirectRate = *o.RedirectRate
+	}
+	if o.ResetRate != nil {
+		p.ResetRate = *o.ResetRate
+	}
+	if o.PanicRate != nil {
+		p.PanicRate = *o.PanicRate
+	}
+	if o.GoawayRate != nil {
+		p.GoawayRate = *o.GoawayRate
+	}
+	if o.ConnCloseRate != nil {
+		p.ConnCloseRate = *o.ConnCloseRate
+	}
+	if o.Outage != nil {
+		p.Outage = *o.Outage
+	}
+	return p
+}
+
+// Layer adjusts a profile for a request.
+type Layer interface {
+	Apply(r *http.Request, p Profile) Profile
+}
+
+// Swap is a layer delegating to a layer that can be replaced at runtime,
+// e.g. when the document defining it is reloaded. The zero value keeps
+// profiles unchanged until a layer is set.
+type Swap struct {
+	layer atomic.Pointer[Layer]
+}
+
+// Set replaces the layer requests are delegated to.
+func (s *Swap) Set(l Layer) {
+	s.layer.Store(&l)
+}
+
+// Apply applies the current layer.
+// Implements Layer.
+func (s *Swap) Apply(r *http.Request, p Profile) Profile {
+	if l := s.layer.Load(); l != nil {
+		return (*l).Apply(r, p)
+	}
+	return p
+}
+
+// Resolver computes the effective profile for a request from a base profile
+// and a stack of layers applied in order.
+type Resolver struct {
+	base   atomic.Pointer[Profile]
+	layers []Layer
+}
+
+// NewResolver creates a resolver for the given base profile and layers.
+func NewResolver(base Profile, layers ...Layer) *Resolver {
+	res := &Resolver{layers: layers}
+	res.base.Store(&base)
+	return res
+}
+
+// SetBase replaces the base profile, e.g. after a configuration reload.
+// Layers keep applying on top of the new base.
+func (res *Resolver) SetBase(base Profile) {
+	res.base.Store(&base)
+}
+
+// Resolve returns the profile in effect for the request.
+func (res *Resolver) Resolve(r *http.Request) Profile {
+	p := *res.base.Load()
+	for _, l := range res.layers {
+		p = l.Apply(r, p)
+	}
+	return p
+}
+
+// Validate checks that all set override fields are within range.
+func (o Override) Validate() error {
+	rates := []struct {
+		name string
+		val  *float64
+	}{
+		{"hang_rate", o.HangRate},
+		{"error_rate", o.ErrorRate},
+		{"bad_request_rate", o.BadRequestRate},
+		{"slow_read_rate", o.SlowReadRate},
+		{"redirect_rate", o.RedirectRate},
+		{"stream_reset_rate", o.ResetRate},
+		{"panic_rate", o.PanicRate},
+		{"goaway_rate", o.GoawayRate},
+		{"conn_close_rate", o.ConnCloseRate},
+	}
+	for _, r := range rates {
+		if r.val != nil && (*r.val < 0 || *r.val > 1) {
+			return fmt.Errorf("%s: must be between 0 and 1, got %v", r.name, *r.val)
+		}
+	}
+
+	durations := []struct {
+		name string
+		val  *time.Duration
+	}{
+		{"response_delay", o.ResponseDelay},
+		{"response_jitter", o.ResponseJitter},
+	}
+	for _, d := range durations {
+		if d.val != nil && *d.val < 0 {
+			return fmt.Errorf("%s: must be >= 0s, got %v", d.name, *d.val)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package bodysize provides receiver behavior rules keyed on request body
+// size, so payload-size failure modes and proxy limits can be reproduced.
+package bodysize
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+
+	"github.com/neox5/tct/internal/behavior"
+)
+
+// File is the on-disk size rules document.
+type File struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule applies to requests whose body size is within [MinBytes, MaxBytes].
+// A zero MaxBytes means no upper bound. Reject answers 413 Payload Too
+// Large; otherwise ExtraDelay is added to the response delay and the
+// override is applied to the remaining pipeline.
+type Rule struct {
+	Name              string        `yaml:"name"`
+	MinBytes          int64         `yaml:"min_bytes"`
+	MaxBytes          int64         `yaml:"max_bytes"`
+	Reject            bool          `yaml:"reject"`
+	ExtraDelay        time.Duration `yaml:"extra_delay"`
+	behavior.Override `yaml:",inline"`
+}
+
+// Rules is an ordered list of size rules.
+type Rules []Rule
+
+// Load reads and validates a size rules file. Both YAML and JSON are accepted.
+// Rules apply in order (the first matching rule wins), so a rule whose range
+// is covered by an earlier rule is rejected as unreachable.
+//
+// Example:
+//
+//	rules:
+//	  - name: large
+//	    min_bytes: 1048576
+//	    max_bytes: 10485759
+//	    extra_delay: 2s
+//	  - name: too-large
+//	    min_bytes: 10485760
+//	    reject: true
+func Load(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read size rules: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Parse parses and validates a size rules document read from source.
+func Parse(data []byte, source string) (Rules, error) {
+	f := &File{}
+	if err := yaml.UnmarshalStrict(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse size rules %s: %w", source, err)
+	}
+
+	if len(f.Rules) == 0 {
+		return nil, fmt.Errorf("invalid size rules %s: at least one rule is required", source)
+	}
+	for i, r := range f.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("invalid size rules %s: rule %d: name is required", source, i)
+		}
+		if r.MinBytes < 0 || r.MaxBytes < 0 || (r.MaxBytes > 0 && r.MaxBytes < r.MinBytes) {
+			return nil, fmt.Errorf("invalid size rules %s: rule %q: invalid byte range [%d, %d]", source, r.Name, r.MinBytes, r.MaxBytes)
+		}
+		if r.ExtraDelay < 0 {
+			return nil, fmt.Errorf("invalid size rules %s: rule %q: extra_delay must be >= 0", source, r.Name)
+		}
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid size rules %s: rule %q: %w", source, r.Name, err)
+		}
+		for _, prev := range f.Rules[:i] {
+			if prev.covers(r) {
+				return nil, fmt.Errorf("invalid size rules %s: rule %q is unreachable, as rule %q matches all its sizes first", source, r.Name, prev.Name)
+			}
+		}
+	}
+
+	return f.Rules, nil
+}
+
+// Match returns the first rule matching the body size, or nil if none.
+func (rs Rules) Match(size int64) *Rule {
+	for i := range rs {
+		r := &rs[i]
+		if size >= r.MinBytes && (r.MaxBytes == 0 || size <= r.MaxBytes) {
+			return r
+		}
+	}
+	return nil
+}
+
+// covers reports whether r matches every body size o matches.
+func (r Rule) covers(o Rule) bool {
+	if o.MinBytes < r.MinBytes {
+		return false
+	}
+	return r.MaxBytes == 0 || (o.MaxBytes != 0 && o.MaxBytes <= r.MaxBytes)
+}
@@ -0,0 +1,50 @@
+// Package brownout provides the partial brownout fault: only a fraction of
+// TCP connections, chosen when they are accepted, receive hangs and errors,
+// simulating a bad backend instance behind a shared VIP.
+package brownout
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/neox5/tct/internal/behavior"
+	"github.com/neox5/tct/internal/metrics"
+	"github.com/neox5/tct/internal/random"
+)
+
+// ctxKey marks the connection context of affected connections.
+type ctxKey struct{}
+
+// Layer decides per connection whether it is affected and clears hang and
+// error rates for requests on unaffected connections.
+type Layer struct {
+	rate float64
+	rng  *random.Rand
+	m    *metrics.ReceiverMetrics
+}
+
+// NewLayer creates a brownout layer affecting the given fraction of
+// connections.
+func NewLayer(rate float64, rng *random.Rand, m *metrics.ReceiverMetrics) *Layer {
+	return &Layer{rate: rate, rng: rng, m: m}
+}
+
+// ConnContext decides at accept time whether the connection is affected
+// and records the decision in its context. Use as http.Server.ConnContext.
+func (l *Layer) ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	affected := l.rng.Float64() < l.rate
+	l.m.RecordBrownoutConn(affected)
+	return context.WithValue(ctx, ctxKey{}, affected)
+}
+
+// Apply keeps the profile for requests on affected connections and removes
+// hangs and errors for all others.
+// Implements behavior.Layer.
+func (l *Layer) Apply(r *http.Request, p behavior.Profile) behavior.Profile {
+	if affected, _ := r.Context().Value(ctxKey{}).(bool); !affected {
+		p.HangRate = 0
+		p.ErrorRate = 0
+	}
+	return p
+}
@@ -0,0 +1,177 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// Manager serves the receiver certificate and swaps in a faulty
+// certificate during configured fault windows.
+type Manager struct {
+	cfg    *config.Config
+	log    *logger.Logger
+	m      *metrics.ReceiverMetrics
+	cert   tls.Certificate
+	faulty tls.Certificate
+	active atomic.Bool
+	client *x509.CertPool
+}
+
+// NewManager loads the configured certificate (or generates a self-signed one
+// for localhost) and prepares the faulty certificate for the configured fault.
+// Fault window management starts immediately if a fault is configured.
+func NewManager(cfg *config.Config, log *logger.Logger, m *metrics.ReceiverMetrics) (*Manager, error) {
+	cert, err := loadOrGenerate(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr := &Manager{
+		cfg:  cfg,
+		log:  log,
+		m:    m,
+		cert: cert,
+	}
+
+	// Load client CA pool for mTLS enforcement
+	if cfg.TLSClientCAFile != "" {
+		if mgr.client, err = LoadPool(cfg.TLSClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.CertFault == "none" {
+		return mgr, nil
+	}
+
+	mgr.faulty, err = generateFaulty(cfg.CertFault, cert.Leaf.DNSNames)
+	if err != nil {
+		return nil, err
+	}
+
+	go mgr.manage()
+
+	return mgr, nil
+}
+
+// TLSConfig returns a server TLS configuration backed by the manager.
+// If a client CA is configured, connections without a valid client
+// certificate are rejected during the handshake.
+func (mgr *Manager) TLSConfig() *tls.Config {
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: mgr.getCertificate,
+	}
+
+	if mgr.client != nil {
+		// Verification happens in VerifyConnection so rejections can be counted
+		tlsConfig.ClientAuth = tls.RequestClientCert
+		tlsConfig.VerifyConnection = mgr.verifyClient(mgr.client)
+	}
+
+	return tlsConfig
+}
+
+// getCertificate returns the faulty certificate while a fault is active.
+func (mgr *Manager) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if mgr.active.Load() {
+		return &mgr.faulty, nil
+	}
+	return &mgr.cert, nil
+}
+
+// setActive sets the fault state and updates the metric.
+func (mgr *Manager) setActive(active bool) {
+	mgr.active.Store(active)
+	mgr.m.SetCertFaultState(active)
+}
+
+// manage runs the certificate fault lifecycle loop.
+// A zero CertFaultFor keeps the fault active once started.
+func (mgr *Manager) manage() {
+	// Wait for initial delay
+	time.Sleep(mgr.cfg.CertFaultAfter)
+
+	for {
+		// Start fault
+		mgr.log.Info("certificate fault started", "fault", mgr.cfg.CertFault, "duration", mgr.cfg.CertFaultFor)
+		mgr.setActive(true)
+		if mgr.cfg.CertFaultFor == 0 {
+			return
+		}
+		time.Sleep(mgr.cfg.CertFaultFor)
+
+		// End fault
+		mgr.log.Info("certificate fault ended", "fault", mgr.cfg.CertFault)
+		mgr.setActive(false)
+
+		// If not repeating, stop
+		if !mgr.cfg.CertFaultRepeat {
+			return
+		}
+
+		// Wait for next cycle
+		time.Sleep(mgr.cfg.CertFaultAfter)
+	}
+}
+
+// loadOrGenerate loads the configured key pair or generates a self-signed
+// certificate for localhost and the local hostname.
+func loadOrGenerate(cfg *config.Config) (tls.Certificate, error) {
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("failed to load TLS key pair: %w", err)
+		}
+		if cert.Leaf == nil {
+			if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+				return tls.Certificate{}, fmt.Errorf("failed to parse TLS certificate: %w", err)
+			}
+		}
+		return cert, nil
+	}
+
+	hosts := []string{"localhost"}
+	if name, err := os.Hostname(); err == nil && name != "localhost" {
+		hosts = append(hosts, name)
+	}
+
+	now := time.Now()
+	return Generate(hosts, now.Add(-time.Hour), now.Add(365*24*time.Hour), nil)
+}
+
+// generateFaulty creates the certificate served during a fault window.
+// Faulty certificates are signed by an ephemeral CA unknown to clients.
+// Go clients check validity period and hostname before the chain, so
+// "expired" and "wrong_host" surface as those errors rather than as an
+// unknown authority.
+func generateFaulty(fault string, hosts []string) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost"}
+	}
+
+	ca, err := GenerateCA("tct chaos CA")
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	switch fault {
+	case "expired":
+		return Generate(hosts, now.Add(-48*time.Hour), now.Add(-24*time.Hour), &ca)
+	case "wrong_host":
+		return Generate([]string{"wrong-host.invalid"}, now.Add(-time.Hour), now.Add(24*time.Hour), &ca)
+	case "self_signed":
+		return Generate(hosts, now.Add(-time.Hour), now.Add(24*time.Hour), nil)
+	default:
+		return tls.Certificate{}, fmt.Errorf("unsupported certificate fault %q", fault)
+	}
+}
@@ -0,0 +1,38 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// verifyClient returns a VerifyConnection callback that rejects connections
+// without a client certificate chaining to pool. Rejections are counted by
+// reason ("missing" or "invalid").
+func (mgr *Manager) verifyClient(pool *x509.CertPool) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			mgr.m.RecordClientAuthFailure("missing")
+			mgr.log.Debug("client certificate missing", "server_name", cs.ServerName)
+			return errors.New("client certificate required")
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+
+		if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+			mgr.m.RecordClientAuthFailure("invalid")
+			mgr.log.Debug("client certificate rejected", "subject", cs.PeerCertificates[0].Subject.String(), "error", err)
+			return fmt.Errorf("invalid client certificate: %w", err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,66 @@
+package certs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// keyPair serves a certificate from files, reloading it when the
+// certificate file changes (e.g. rotated by cert-manager).
+type keyPair struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// ServerTLSConfig returns a server TLS configuration serving the key pair
+// from certFile and keyFile. The files are read again after the
+// certificate file changes; a failed reload keeps the previous certificate.
+func ServerTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	kp := &keyPair{certFile: certFile, keyFile: keyFile}
+	if _, err := kp.load(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: kp.getCertificate,
+	}, nil
+}
+
+// getCertificate returns the current certificate, reloading it if the
+// certificate file changed.
+func (kp *keyPair) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	kp.mu.Lock()
+	cert, modTime := kp.cert, kp.modTime
+	kp.mu.Unlock()
+
+	if info, err := os.Stat(kp.certFile); err == nil && !info.ModTime().Equal(modTime) {
+		if reloaded, err := kp.load(); err == nil {
+			cert = reloaded
+		}
+	}
+	return cert, nil
+}
+
+// load reads the key pair and records the modification time of the
+// certificate file.
+func (kp *keyPair) load() (*tls.Certificate, error) {
+	info, err := os.Stat(kp.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server TLS certificate: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(kp.certFile, kp.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server TLS key pair: %w", err)
+	}
+
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.cert, kp.modTime = &cert, info.ModTime()
+	return &cert, nil
+}
@@ -1,31 +1,268 @@
 // Package config defines configuration structures for tct.
-// Configuration is loaded from environment variables via the env package.
+// Configuration is loaded from environment variables via the env package,
+// optionally layered over values from a config file.
 package config
 
-import "time"
+import (
+	"net/url"
+	"time"
+
+	"github.com/neox5/tct/internal/env"
+)
 
 // Config holds the complete application configuration.
-// All fields are at the top level. The Mode field determines which
-// subset of fields are relevant for the current execution.
+// Common fields are at the top level; mode-specific fields are grouped in
+// embedded sections, so they are accessed as if they were top-level too.
+// The Mode field determines which section is relevant for the current
+// execution.
 type Config struct {
-	// Common fields
-	Mode     string `env:"TCT_MODE,required"`
-	LogLevel string `env:"TCT_LOG_LEVEL,default=info"`
-
-	// Sender fields
-	SenderPort     int           `env:"TCT_SENDER_PORT,default=9090,min=1,max=65535"`
-	ReceiverHost   string        `env:"TCT_RECEIVER_HOST,default=localhost"`
-	ReceiverPort   int           `env:"TCT_RECEIVER_PORT,default=8080,min=1,max=65535"`
-	RPS            float64       `env:"TCT_RPS,default=1.0,min=0"`
-	StartDelay     time.Duration `env:"TCT_START_DELAY,default=0s"`
-	RequestTimeout time.Duration `env:"TCT_REQUEST_TIMEOUT,default=2s,min=0s"`
-
-	// Receiver fields
-	ResponseDelay  time.Duration `env:"TCT_RESPONSE_DELAY,default=0s,min=0s"`
-	ResponseJitter time.Duration `env:"TCT_RESPONSE_JITTER,default=0s,min=0s"`
-	HangRate       float64       `env:"TCT_HANG_RATE,default=0,min=0,max=1"`
-	ErrorRate      float64       `env:"TCT_ERROR_RATE,default=0,min=0,max=1"`
-	OutageAfter    time.Duration `env:"TCT_OUTAGE_AFTER,default=0s,min=0s"`
-	OutageFor      time.Duration `env:"TCT_OUTAGE_FOR,default=0s,min=0s"`
-	OutageRepeat   bool          `env:"TCT_OUTAGE_REPEAT,default=false"`
+	// Common fields (HTTP2 enables h2c with prior knowledge on cleartext
+	// connections of the sender and the receiver traffic servers, so HTTP/2
+	// and gRPC clients can reach the receiver without TLS, a zero Seed is
+	// replaced by a time-based seed at startup, StrictEnv selects how unknown
+	// TCT_ variables are reported)
+	Mode      string `env:"TCT_MODE,required,oneof=sender|receiver|echo"`
+	LogLevel  string `env:"TCT_LOG_LEVEL,default=info"`
+	HTTP2     bool   `env:"TCT_HTTP2,default=false"`
+	Seed      int64  `env:"TCT_RANDOM_SEED,default=0"`
+	StrictEnv string `env:"TCT_STRICT_ENV,default=warn,oneof=off|warn|error"`
+
+	// Native (sparse) latency histograms in addition to classic buckets
+	NativeHistograms bool `env:"TCT_NATIVE_HISTOGRAMS,default=false"`
+
+	// Metric name prefix replacing tct and constant labels added to all
+	// exposed metrics (e.g. cluster=eu1,experiment=retry-storm)
+	MetricsNamespace string            `env:"TCT_METRICS_NAMESPACE,default=tct,pattern=[a-zA-Z_][a-zA-Z0-9_]*"`
+	MetricsLabels    map[string]string `env:"TCT_METRICS_LABELS"`
+
+	// Latency heatmap slice width and number of slices kept (0 = disabled)
+	HeatmapSlice  time.Duration `env:"TCT_HEATMAP_SLICE,default=10s,min=1s"`
+	HeatmapSlices int           `env:"TCT_HEATMAP_SLICES,default=360,min=0"`
+
+	// Experiment event log size (0 = disabled) and webhook receiving each
+	// event as JSON
+	EventsSize    int      `env:"TCT_EVENTS_SIZE,default=1000,min=0"`
+	EventsWebhook *url.URL `env:"TCT_EVENTS_WEBHOOK,fromFile,secret,scheme=http|https"`
+
+	// Maximum distinct values of dynamic labels (target, profile) before
+	// folding into "other" (0 = unlimited)
+	MetricsLabelLimit int `env:"TCT_METRICS_LABEL_LIMIT,default=100,min=0"`
+
+	// Profiling endpoints (/debug/pprof/) on the observability server
+	PprofEnabled bool `env:"TCT_PPROF_ENABLED,default=false"`
+
+	// Go runtime (go_*) and process (process_*) metrics
+	GoMetrics      bool `env:"TCT_GO_METRICS,default=true"`
+	ProcessMetrics bool `env:"TCT_PROCESS_METRICS,default=true"`
+
+	// Address of the interface servers listen on (all interfaces if empty)
+	BindAddr string `env:"TCT_BIND_ADDR,pattern=[][A-Za-z0-9.:-]+"`
+
+	// Fraction of requests to all servers logged as "http request" lines
+	HTTPLogSample float64 `env:"TCT_HTTP_LOG_SAMPLE,default=0,min=0,max=1"`
+
+	// HTTP server timeouts of all servers (0 = none). Read and write
+	// timeouts also cut off slow-read, delay, and hang faults. The drain
+	// timeout bounds the wait for in-flight requests on shutdown (0 = wait
+	// until they complete)
+	ServerReadTimeout       time.Duration `env:"TCT_SERVER_READ_TIMEOUT,default=0s,min=0s"`
+	ServerReadHeaderTimeout time.Duration `env:"TCT_SERVER_READ_HEADER_TIMEOUT,default=10s,min=0s"`
+	ServerWriteTimeout      time.Duration `env:"TCT_SERVER_WRITE_TIMEOUT,default=0s,min=0s"`
+	ServerIdleTimeout       time.Duration `env:"TCT_SERVER_IDLE_TIMEOUT,default=120s,min=0s"`
+	DrainTimeout            time.Duration `env:"TCT_DRAIN_TIMEOUT,default=5s,min=0s"`
+
+	// HTTPS key pair for the observability servers and, unless the receiver
+	// has TLSEnabled (certificate faults), the receiver traffic servers
+	ServerTLSCert string `env:"TCT_SERVER_TLS_CERT,required_if=TCT_SERVER_TLS_KEY!="`
+	ServerTLSKey  string `env:"TCT_SERVER_TLS_KEY,required_if=TCT_SERVER_TLS_CERT!="`
+
+	// OpenTelemetry export (none or otlp) to the OTLP/HTTP collector at
+	// OTelEndpoint (the standard OTEL_EXPORTER_OTLP_* variables apply if
+	// unset), pushing metrics every OTelInterval and tracing the fraction
+	// OTelTraceRatio of requests
+	OTelExporter   string        `env:"TCT_OTEL_EXPORTER,default=none,oneof=none|otlp"`
+	OTelEndpoint   *url.URL      `env:"TCT_OTEL_ENDPOINT,scheme=http|https"`
+	OTelInterval   time.Duration `env:"TCT_OTEL_INTERVAL,default=15s,min=1s"`
+	OTelTraceRatio float64       `env:"TCT_OTEL_TRACE_RATIO,default=1.0,min=0,max=1"`
+
+	// Prometheus Pushgateway metrics are pushed to every PushInterval and at
+	// shutdown (disabled if unset), grouped by PushJob and mode
+	PushgatewayURL *url.URL      `env:"TCT_PUSHGATEWAY_URL,fromFile,secret,scheme=http|https"`
+	PushJob        string        `env:"TCT_PUSH_JOB,default=tct,maxlen=128,pattern=[A-Za-z0-9_.-]+"`
+	PushInterval   time.Duration `env:"TCT_PUSH_INTERVAL,default=15s,min=1s"`
+
+	// Interval of summary log lines with request counts, errors, and
+	// latency percentiles (0 disables them)
+	StatsInterval time.Duration `env:"TCT_STATS_INTERVAL,default=0s,min=0s"`
+
+	// StatsD agent (host:port) counters and gauges are flushed to every
+	// StatsDInterval and latencies are sent to as timings (disabled if
+	// empty); dogstatsd carries labels as tags
+	StatsDAddr     string        `env:"TCT_STATSD_ADDR,pattern=[][A-Za-z0-9.:-]+:[0-9]+"`
+	StatsDFormat   string        `env:"TCT_STATSD_FORMAT,default=statsd,oneof=statsd|dogstatsd"`
+	StatsDInterval time.Duration `env:"TCT_STATSD_INTERVAL,default=10s,min=1s"`
+
+	// Config file the configuration was layered over (nil if none), the
+	// interval at which it and the documents read from files or URLs are
+	// checked for changes (0 disables watching), and the file the resolved
+	// configuration is persisted to for comparison with the next run (empty
+	// disables snapshots)
+	File                *File
+	ConfigWatchInterval time.Duration `env:"TCT_CONFIG_WATCH_INTERVAL,default=0s,min=0s"`
+	StateFile           string        `env:"TCT_STATE_FILE"`
+
+	// Profile selection header (sender sets it, receiver selects by it)
+	ProfileHeader string `env:"TCT_PROFILE_HEADER,default=X-TCT-Profile,maxlen=128,pattern=[A-Za-z0-9-]+"`
+
+	// Receiver port (the receiver listens on it, the sender targets it, and
+	// echo mode serves observability endpoints on it)
+	ReceiverPort int `env:"TCT_RECEIVER_PORT,default=8080,min=1,max=65535"`
+
+	// Transit delay measurement (the sender sends its send time, the receiver
+	// records the delay on arrival); requires synchronized clocks, as clock
+	// skew shifts every measurement
+	TransitTime bool `env:"TCT_TRANSIT_TIME,default=false"`
+
+	// Service level objective (fraction of good requests, 0 disables burn
+	// rate metrics); requests slower than a positive SLOLatency count as bad
+	SLOObjective float64         `env:"TCT_SLO_OBJECTIVE,default=0,min=0,max=0.999999"`
+	SLOLatency   time.Duration   `env:"TCT_SLO_LATENCY,default=0s,min=0s"`
+	SLOWindows   []time.Duration `env:"TCT_SLO_WINDOWS,minlen=1,min=10s,default=5m,1h,6h"`
+
+	// Scenario shared by sender (request rate) and receiver (behavior)
+	ScenarioFile string `env:"TCT_SCENARIO_FILE"`
+
+	// Mode-specific sections (settings of inactive modes are rejected)
+	SenderConfig
+	ReceiverConfig
+	EchoConfig
+}
+
+// SenderConfig holds the settings used only in sender mode. A TargetURL
+// replaces the target derived from ReceiverHost, ReceiverPort, and
+// ReceiverTLS. An RPSSchedule (e.g. "5m@10,2m@50") varies the request rate
+// over time; RPS applies once it ends unless it repeats. The Assert settings
+// are evaluated at shutdown into a verdict written to VerdictFile.
+type SenderConfig struct {
+	SenderPort      int           `env:"TCT_SENDER_PORT,default=9090,min=1,max=65535"`
+	TargetURL       *url.URL      `env:"TCT_TARGET_URL,scheme=http|https"`
+	ReceiverHost    string        `env:"TCT_RECEIVER_HOST,default=localhost,pattern=[][A-Za-z0-9.:-]+"`
+	RPS             float64       `env:"TCT_RPS,default=1.0,min=0"`
+	RPSSchedule     env.Schedule  `env:"TCT_RPS_SCHEDULE"`
+	RPSRepeat       bool          `env:"TCT_RPS_SCHEDULE_REPEAT,default=false"`
+	Profile         string        `env:"TCT_PROFILE,maxlen=64,pattern=[A-Za-z0-9_.-]+"`
+	StartDelay      time.Duration `env:"TCT_START_DELAY,default=0s"`
+	RequestTimeout  time.Duration `env:"TCT_REQUEST_TIMEOUT,default=2s,min=0s"`
+	FollowRedirects bool          `env:"TCT_FOLLOW_REDIRECTS,default=true"`
+	MaxRedirects    int           `env:"TCT_MAX_REDIRECTS,default=10,min=0"`
+	ReceiverTLS     bool          `env:"TCT_RECEIVER_TLS,default=false"`
+	TLSCAFile       string        `env:"TCT_TLS_CA_FILE"`
+	TLSInsecure     bool          `env:"TCT_TLS_INSECURE,default=false"`
+	TLSClientCert   string        `env:"TCT_TLS_CLIENT_CERT_FILE,required_if=TCT_TLS_CLIENT_KEY_FILE!="`
+	TLSClientKey    string        `env:"TCT_TLS_CLIENT_KEY_FILE,required_if=TCT_TLS_CLIENT_CERT_FILE!="`
+	AssertErrorRate float64       `env:"TCT_ASSERT_ERROR_RATE,default=1,min=0,max=1"`
+	AssertP99       time.Duration `env:"TCT_ASSERT_P99,default=0s,min=0s"`
+	AssertNoResults []string      `env:"TCT_ASSERT_NO_RESULTS,maxlen=16,pattern=[a-z0-9_]+"`
+	VerdictFile     string        `env:"TCT_VERDICT_FILE,default=stderr"`
+}
+
+// ReceiverConfig holds the settings used only in receiver mode. A non-zero
+// AdminPort serves observability and control endpoints on a separate port
+// from traffic, which stays up until the traffic servers have stopped; it
+// listens on AdminBindAddr if set (e.g. 127.0.0.1), otherwise on BindAddr.
+// A ReceiverSocket path serves traffic on a Unix socket in addition to
+// ReceiverPort (e.g. behind a sidecar proxy). MaxConnections limits the open
+// connections of each traffic server; further connections wait in the
+// accept backlog. On shutdown, traffic servers drain for DrainDelay:
+// /readyz fails and new requests get 503 while in-flight ones finish.
+type ReceiverConfig struct {
+	AdminPort               int            `env:"TCT_ADMIN_PORT,default=0,min=0,max=65535"`
+	AdminBindAddr           string         `env:"TCT_ADMIN_BIND_ADDR,pattern=[][A-Za-z0-9.:-]+"`
+	ReceiverSocket          string         `env:"TCT_RECEIVER_SOCKET,maxlen=104"`
+	MaxConnections          int            `env:"TCT_MAX_CONNECTIONS,default=0,min=0"`
+	DrainDelay              time.Duration  `env:"TCT_DRAIN_DELAY,default=0s,min=0s"`
+	ResponseDelay           time.Duration  `env:"TCT_RESPONSE_DELAY,default=0s,min=0s"`
+	ResponseJitter          time.Duration  `env:"TCT_RESPONSE_JITTER,default=0s,min=0s"`
+	HangRate                float64        `env:"TCT_HANG_RATE,default=0,min=0,max=1"`
+	ErrorRate               float64        `env:"TCT_ERROR_RATE,default=0,min=0,max=1"`
+	ErrorModel              string         `env:"TCT_ERROR_MODEL,default=bernoulli,oneof=bernoulli|burst"`
+	BurstEnterRate          float64        `env:"TCT_BURST_ENTER_RATE,default=0.01,min=0,max=1"`
+	BurstExitRate           float64        `env:"TCT_BURST_EXIT_RATE,default=0.1,min=0,max=1"`
+	BurstErrorRate          float64        `env:"TCT_BURST_ERROR_RATE,default=1,min=0,max=1"`
+	Outage                  OutageConfig   `envPrefix:"TCT_OUTAGE_"`
+	RedirectRate            float64        `env:"TCT_REDIRECT_RATE,default=0,min=0,max=1"`
+	RedirectCode            int            `env:"TCT_REDIRECT_CODE,default=302,oneof=301|302|307|308"`
+	RedirectDepth           int            `env:"TCT_REDIRECT_DEPTH,default=1,min=1"`
+	StreamResetRate         float64        `env:"TCT_STREAM_RESET_RATE,default=0,min=0,max=1"`
+	PanicRate               float64        `env:"TCT_PANIC_RATE,default=0,min=0,max=1"`
+	PanicRecover            bool           `env:"TCT_PANIC_RECOVER,default=true"`
+	GoawayRate              float64        `env:"TCT_GOAWAY_RATE,default=0,min=0,max=1"`
+	ConnCloseRate           float64        `env:"TCT_CONN_CLOSE_RATE,default=0,min=0,max=1"`
+	KeepAliveOffAfter       time.Duration  `env:"TCT_KEEPALIVE_OFF_AFTER,default=0s,min=0s"`
+	KeepAliveOffFor         time.Duration  `env:"TCT_KEEPALIVE_OFF_FOR,default=0s,min=0s,required_if=TCT_KEEPALIVE_OFF_AFTER!=0s"`
+	KeepAliveOffRepeat      bool           `env:"TCT_KEEPALIVE_OFF_REPEAT,default=false"`
+	BrownoutConnRate        float64        `env:"TCT_BROWNOUT_CONN_RATE,default=0,min=0,max=1"`
+	BadRequestRate          float64        `env:"TCT_BAD_REQUEST_RATE,default=0,min=0,max=1"`
+	SlowReadRate            float64        `env:"TCT_SLOW_READ_RATE,default=0,min=0,max=1"`
+	SlowReadBPS             env.ByteSize   `env:"TCT_SLOW_READ_BYTES_PER_SEC,default=0,min=0"`
+	ResponseSize            env.ByteSize   `env:"TCT_RESPONSE_SIZE,default=0,min=0"`
+	ResponseCompressibility float64        `env:"TCT_RESPONSE_COMPRESSIBILITY,default=0.5,min=0,max=1"`
+	Compression             string         `env:"TCT_COMPRESSION,default=off,oneof=off|auto|gzip|deflate"`
+	ProfilesFile            string         `env:"TCT_PROFILES_FILE"`
+	PortProfiles            map[int]string `env:"TCT_PORT_PROFILES"`
+	UpstreamURL             *url.URL       `env:"TCT_UPSTREAM_URL,fromFile,secret,scheme=http|https"`
+	UpstreamTimeout         time.Duration  `env:"TCT_UPSTREAM_TIMEOUT,default=1s,min=0s"`
+	CrashAfterRequests      int            `env:"TCT_CRASH_AFTER_REQUESTS,default=0,min=0"`
+	CrashAfter              time.Duration  `env:"TCT_CRASH_AFTER,default=0s,min=0s"`
+	CrashExitCode           int            `env:"TCT_CRASH_EXIT_CODE,default=1,min=0,max=255"`
+	CrashPanic              bool           `env:"TCT_CRASH_PANIC,default=false"`
+	ScheduleFile            string         `env:"TCT_SCHEDULE_FILE"`
+	SizeRulesFile           string         `env:"TCT_SIZE_RULES_FILE"`
+	ErrorBody               string         `env:"TCT_ERROR_BODY,default=text,oneof=text|json"`
+	ErrorBodyTemplate       string         `env:"TCT_ERROR_BODY_TEMPLATE,fromFile"`
+	ShedCPU                 float64        `env:"TCT_SHED_CPU,default=0,min=0,max=1"`
+	ShedGoroutines          int            `env:"TCT_SHED_GOROUTINES,default=0,min=0"`
+	ShedInterval            time.Duration  `env:"TCT_SHED_INTERVAL,default=1s,min=100ms"`
+	ValidateContentType     string         `env:"TCT_VALIDATE_CONTENT_TYPE"`
+	ValidateHeaders         []string       `env:"TCT_VALIDATE_HEADERS,maxlen=32,pattern=[A-Za-z0-9-]+"`
+	ValidateJSON            bool           `env:"TCT_VALIDATE_JSON,default=false"`
+	CacheControl            string         `env:"TCT_CACHE_CONTROL"`
+	CacheETag               bool           `env:"TCT_CACHE_ETAG,default=false"`
+	CacheLastModified       bool           `env:"TCT_CACHE_LAST_MODIFIED,default=false"`
+	DedupHeader             string         `env:"TCT_DEDUP_HEADER,default=Idempotency-Key,maxlen=128,pattern=[A-Za-z0-9-]+"`
+	DedupSize               int            `env:"TCT_DEDUP_SIZE,default=0,min=0"`
+	DedupMode               string         `env:"TCT_DEDUP_MODE,default=count,oneof=count|reject|replay"`
+	AccessLog               string         `env:"TCT_ACCESS_LOG"`
+	ServerTiming            bool           `env:"TCT_SERVER_TIMING,default=false"`
+	InspectSize             int            `env:"TCT_INSPECT_SIZE,default=100,min=0"`
+	TLSEnabled              bool           `env:"TCT_TLS_ENABLED,default=false"`
+	TLSCertFile             string         `env:"TCT_TLS_CERT_FILE,required_if=TCT_TLS_KEY_FILE!="`
+	TLSKeyFile              string         `env:"TCT_TLS_KEY_FILE,required_if=TCT_TLS_CERT_FILE!="`
+	TLSClientCAFile         string         `env:"TCT_TLS_CLIENT_CA_FILE"`
+	CertFault               string         `env:"TCT_CERT_FAULT,default=none,oneof=none|expired|wrong_host|self_signed"`
+	CertFaultAfter          time.Duration  `env:"TCT_CERT_FAULT_AFTER,default=0s,min=0s"`
+	CertFaultFor            time.Duration  `env:"TCT_CERT_FAULT_FOR,default=0s,min=0s"`
+	CertFaultRepeat         bool           `env:"TCT_CERT_FAULT_REPEAT,default=false"`
+}
+
+// EchoConfig holds the settings used only in echo mode (observability
+// endpoints are served on ReceiverPort).
+type EchoConfig struct {
+	EchoProtocol  string        `env:"TCT_ECHO_PROTOCOL,default=tcp,oneof=tcp|udp"`
+	EchoPort      int           `env:"TCT_ECHO_PORT,default=7070,min=1,max=65535"`
+	EchoDelay     time.Duration `env:"TCT_ECHO_DELAY,default=0s,min=0s"`
+	EchoDropRate  float64       `env:"TCT_ECHO_DROP_RATE,default=0,min=0,max=1"`
+	EchoResetRate float64       `env:"TCT_ECHO_RESET_RATE,default=0,min=0,max=1"`
+}
+
+// OutageConfig holds the scheduled outage lifecycle and how outages
+// present to clients ("hang" holds requests, "refuse" closes the listener).
+// Besides the After/For cycle, At lists offsets since startup at which
+// additional outages of length For start (e.g. "1m,5m,12m").
+type OutageConfig struct {
+	After  time.Duration   `env:"AFTER,default=0s,min=0s"`
+	For    time.Duration   `env:"FOR,default=0s,min=0s,required_if=AFTER!=0s"`
+	Repeat bool            `env:"REPEAT,default=false"`
+	At     []time.Duration `env:"AT"`
+	Mode   string          `env:"MODE,default=hang,oneof=hang|refuse"`
 }
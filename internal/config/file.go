@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// envPrefix is the prefix of all configuration environment variables.
+const envPrefix = "TCT_"
+
+// sections lists the structured documents that may be embedded in a config
+// file. A non-empty wrapper nests the section value under that key to form
+// the document expected by the loader (e.g. profiles files have a top-level
+// "profiles" key).
+var sections = map[string]string{
+	"scenario":   "",
+	"schedule":   "",
+	"profiles":   "profiles",
+	"size_rules": "rules",
+}
+
+// File holds values loaded from a config file.
+type File struct {
+	src      Source            // as of the loaded content
+	values   map[string]string // keyed by environment variable name
+	sections map[string][]byte // embedded documents keyed by section name
+}
+
+// LoadFile reads a YAML or JSON config file. Keys are environment variable
+// names without the TCT_ prefix in lower case; keys listed in known are
+// accepted as values, and the sections scenario, schedule, profiles, and
+// size_rules embed the documents otherwise read from the matching *_FILE.
+//
+// path may also be an HTTP(S) URL to fetch the config from a central
+// controller.
+//
+// Example:
+//
+//	mode: receiver
+//	error_rate: 0.1
+//	response_delay: 50ms
+//	scenario:
+//	  phases:
+//	    - name: degraded
+//	      duration: 2m
+//	      error_rate: 0.5
+func LoadFile(path string, known []string) (*File, error) {
+	src := NewSource(path)
+	if _, err := src.Refresh(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return LoadSource(src, known)
+}
+
+// LoadSource parses a config file from the last read of src (see LoadFile),
+// e.g. after Refresh found it changed.
+func LoadSource(src *Source, known []string) (*File, error) {
+	path := src.Name()
+	raw := map[string]any{}
+	if err := yaml.Unmarshal(src.Data(), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	f := &File{src: *src, values: map[string]string{}, sections: map[string][]byte{}}
+	for key, v := range raw {
+		if wrapper, ok := sections[key]; ok {
+			if wrapper != "" {
+				v = map[string]any{wrapper: v}
+			}
+			doc, err := yaml.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid config file %s: section %q: %w", path, key, err)
+			}
+			f.sections[key] = doc
+			continue
+		}
+
+		envKey := envPrefix + strings.ToUpper(key)
+		if !slices.Contains(known, envKey) {
+			return nil, fmt.Errorf("invalid config file %s: unknown key %q", path, key)
+		}
+		switch v := v.(type) {
+		case map[any]any:
+			// Maps map to comma-separated key=value pairs
+			items := make([]string, 0, len(v))
+			for k, item := range v {
+				items = append(items, fmt.Sprintf("%v=%v", k, item))
+			}
+			slices.Sort(items)
+			f.values[envKey] = strings.Join(items, ",")
+		case []any:
+			// Lists map to comma-separated values
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			f.values[envKey] = strings.Join(items, ",")
+		case nil:
+		default:
+			f.values[envKey] = fmt.Sprint(v)
+		}
+	}
+
+	return f, nil
+}
+
+// Lookup returns the value for an environment variable name.
+// Usable as env.Lookup.
+func (f *File) Lookup(key string) (string, bool) {
+	v, ok := f.values[key]
+	return v, ok
+}
+
+// Section returns the embedded document for the section, if present.
+func (f *File) Section(name string) ([]byte, bool) {
+	doc, ok := f.sections[name]
+	return doc, ok
+}
+
+// Path returns the path or URL the file was loaded from.
+func (f *File) Path() string {
+	return f.src.path
+}
+
+// Source returns a source of the file as of the loaded content, to poll it
+// for changes.
+func (f *File) Source() *Source {
+	src := f.src
+	return &src
+}
+
+// Document returns the source of the document configured by path (a file
+// or HTTP(S) URL), read once, or, if path is empty, of the section of the
+// same purpose embedded in the config file. Returns nil if neither is set.
+func (c *Config) Document(path, section string) (*Source, error) {
+	if path != "" {
+		src := NewSource(path)
+		if _, err := src.Refresh(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return src, nil
+	}
+	if c.File != nil {
+		if doc, ok := c.File.Section(section); ok {
+			return &Source{name: fmt.Sprintf("%s (%s)", c.File.Path(), section), data: doc}, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxRemoteSize limits the size of remote documents.
+const maxRemoteSize = 10 << 20
+
+// remoteClient fetches remote documents.
+var remoteClient = &http.Client{Timeout: 10 * time.Second}
+
+// Source is a document read from a local file or an HTTP(S) URL, or
+// embedded in the config file. Refresh polls it for changes. A Source is
+// not safe for concurrent use.
+type Source struct {
+	path    string // empty if embedded
+	name    string // description for error messages
+	data    []byte
+	version string // identifies the content of data (see Refresh)
+}
+
+// NewSource returns the source of the document at path, a file or an
+// HTTP(S) URL. The document is read by the first Refresh.
+func NewSource(path string) *Source {
+	return &Source{path: path, name: path}
+}
+
+// Name returns a description of the source for error messages.
+func (s *Source) Name() string {
+	return s.name
+}
+
+// Data returns the content of the last read.
+func (s *Source) Data() []byte {
+	return s.data
+}
+
+// Refresh reads the document if it changed since the last read and reports
+// whether it did; the first call always reads it. Changes are detected by
+// the modification time of local files and by the ETag or Last-Modified
+// time of remote documents, which are requested conditionally so unchanged
+// documents are not transferred (or by a content hash if the server sends
+// neither). On error the last content is kept. Embedded documents never
+// change.
+func (s *Source) Refresh() (bool, error) {
+	if s.path == "" {
+		return false, nil
+	}
+	data, version, err := fetch(s.path, s.version)
+	if err != nil || data == nil {
+		return false, err
+	}
+	s.data, s.version = data, version
+	return true, nil
+}
+
+// isRemote reports whether path is an HTTP(S) URL rather than a file path.
+func isRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// fetch reads the local file or fetches the remote document at path unless
+// its version is still prev, and returns it with its version. Returns nil
+// data if the document is unchanged.
+func fetch(path, prev string) ([]byte, string, error) {
+	if !isRemote(path) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, "", err
+		}
+		version := info.ModTime().String()
+		if version == prev {
+			return nil, prev, nil
+		}
+		data, err := os.ReadFile(path)
+		return data, version, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if modified, ok := strings.CutPrefix(prev, "modified:"); ok {
+		req.Header.Set("If-Modified-Since", modified)
+	} else if prev != "" && !strings.HasPrefix(prev, "sha256:") {
+		req.Header.Set("If-None-Match", prev)
+	}
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, prev, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := readRemote(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	version, ok := validator(resp)
+	if !ok {
+		version = contentVersion(data)
+	}
+	if version == prev {
+		return nil, prev, nil
+	}
+	return data, version, nil
+}
+
+// readRemote reads a remote document, failing if it exceeds maxRemoteSize
+// rather than truncating it.
+func readRemote(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxRemoteSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRemoteSize {
+		return nil, fmt.Errorf("document exceeds %d bytes", maxRemoteSize)
+	}
+	return data, nil
+}
+
+// validator returns the version of a remote document given by the cache
+// validators of its response, preferring the ETag.
+func validator(resp *http.Response) (string, bool) {
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return etag, true
+	}
+	if modified := resp.Header.Get("Last-Modified"); modified != "" {
+		return "modified:" + modified, true
+	}
+	return "", false
+}
+
+// contentVersion returns the version of a document given by its content.
+func contentVersion(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
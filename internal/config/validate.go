@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// labelName matches valid Prometheus label names.
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Validate checks that outage windows have a length.
+// Implements env.Validator.
+func (c *OutageConfig) Validate() error {
+	if len(c.At) > 0 && c.For == 0 {
+		return fmt.Errorf("TCT_OUTAGE_AT requires TCT_OUTAGE_FOR")
+	}
+	return nil
+}
+
+// Validate checks receiver settings that depend on each other.
+// Implements env.Validator.
+func (c *ReceiverConfig) Validate() error {
+	// Certificate faults and client authentication need TLS
+	if c.CertFault != "none" && !c.TLSEnabled {
+		return fmt.Errorf("TCT_CERT_FAULT requires TCT_TLS_ENABLED")
+	}
+	if c.TLSClientCAFile != "" && !c.TLSEnabled {
+		return fmt.Errorf("TCT_TLS_CLIENT_CA_FILE requires TCT_TLS_ENABLED")
+	}
+
+	// Refusing connections needs a separate admin port so metrics and
+	// control endpoints stay reachable
+	if c.Outage.Mode == "refuse" && c.AdminPort == 0 {
+		return fmt.Errorf("TCT_OUTAGE_MODE=refuse requires TCT_ADMIN_PORT")
+	}
+
+	return nil
+}
+
+// Validate checks mode settings that depend on common settings or the
+// config file. Implements env.Validator.
+func (c *Config) Validate() error {
+	for name := range c.MetricsLabels {
+		if !labelName.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid metrics label name %q in TCT_METRICS_LABELS", name)
+		}
+	}
+
+	// Each burn rate window is exported as its own series
+	for i, window := range c.SLOWindows {
+		if slices.Contains(c.SLOWindows[:i], window) {
+			return fmt.Errorf("duplicate SLO window %s in TCT_SLO_WINDOWS", window)
+		}
+	}
+
+	// The scenario and the rate schedule would both set the request rate
+	if c.Mode == "sender" && len(c.RPSSchedule) > 0 && (c.ScenarioFile != "" || c.hasSection("scenario")) {
+		return fmt.Errorf("TCT_RPS_SCHEDULE cannot be combined with a scenario")
+	}
+
+	if c.Mode != "receiver" {
+		return nil
+	}
+
+	if c.AdminBindAddr != "" && c.AdminPort == 0 {
+		return fmt.Errorf("TCT_ADMIN_BIND_ADDR requires TCT_ADMIN_PORT")
+	}
+	if c.AdminPort != 0 && c.AdminPort == c.ReceiverPort {
+		return fmt.Errorf("TCT_ADMIN_PORT must differ from TCT_RECEIVER_PORT")
+	}
+
+	// Port personalities select profiles from the profiles file
+	if len(c.PortProfiles) > 0 && c.ProfilesFile == "" && !c.hasSection("profiles") {
+		return fmt.Errorf("TCT_PORT_PROFILES requires TCT_PROFILES_FILE or a profiles section in the config file")
+	}
+	for port, name := range c.PortProfiles {
+		if port < 1 || port > 65535 || name == "" {
+			return fmt.Errorf("invalid port profile %d=%q (must be 'port=profile')", port, name)
+		}
+		if port == c.ReceiverPort || port == c.AdminPort {
+			return fmt.Errorf("port profile port %d conflicts with receiver or admin port", port)
+		}
+	}
+
+	return nil
+}
+
+// hasSection reports whether the config file embeds the named section.
+func (c *Config) hasSection(name string) bool {
+	if c.File == nil {
+		return false
+	}
+	_, ok := c.File.Section(name)
+	return ok
+}
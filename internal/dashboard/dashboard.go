@@ -0,0 +1,203 @@
+// Package dashboard generates Grafana dashboards for the tct metrics, so
+// every experiment environment gets the same panels without building them
+// by hand.
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// datasourceInput is the import input standing in for the Prometheus data
+// source when no data source UID is given.
+const datasourceInput = "${DS_PROMETHEUS}"
+
+// panel describes a time series panel.
+type panel struct {
+	title   string
+	unit    string
+	queries []query
+}
+
+// query is a PromQL expression with its legend.
+type query struct {
+	expr   string
+	legend string
+}
+
+// rows lists the panels of each mode.
+var rows = map[string][]panel{
+	"sender": {
+		{"Request rate by result", "reqps", []query{
+			{`sum by (result) (rate(tct_sender_requests_total{$sel}[$__rate_interval]))`, "{{result}}"},
+		}},
+		{"Error ratio", "percentunit", []query{
+			{`sum(rate(tct_sender_requests_total{$sel,result!="ok"}[$__rate_interval])) / sum(rate(tct_sender_requests_total{$sel}[$__rate_interval]))`, "errors"},
+		}},
+		{"Response time", "s", quantiles("tct_sender_response_time_seconds")},
+		{"In-flight requests", "short", []query{
+			{`sum(tct_sender_inflight{$sel})`, "in-flight"},
+		}},
+		{"Response size", "bytes", quantiles("tct_sender_response_size_bytes")},
+		{"SLO burn rate", "short", []query{
+			{`max by (window) (tct_sender_slo_burn_rate{$sel})`, "{{window}}"},
+		}},
+		{"Active phases", "short", []query{
+			{`max by (source, phase) (tct_sender_phase{$sel})`, "{{source}}: {{phase}}"},
+		}},
+	},
+	"receiver": {
+		{"Request rate by outcome", "reqps", []query{
+			{`sum by (outcome) (rate(tct_receiver_requests_total{$sel}[$__rate_interval]))`, "{{outcome}}"},
+		}},
+		{"Error ratio", "percentunit", []query{
+			{`sum(rate(tct_receiver_requests_total{$sel,outcome!="ok"}[$__rate_interval])) / sum(rate(tct_receiver_requests_total{$sel}[$__rate_interval]))`, "errors"},
+		}},
+		{"Handler time", "s", quantiles("tct_receiver_handler_time_seconds")},
+		{"Handler time p99 by fault", "s", []query{
+			{`histogram_quantile(0.99, sum by (le, fault) (rate(tct_receiver_handler_time_seconds_bucket{$sel}[$__rate_interval])))`, "{{fault}}"},
+		}},
+		{"Transit time", "s", quantiles("tct_receiver_transit_time_seconds")},
+		{"In-flight and hung requests", "short", []query{
+			{`sum(tct_receiver_inflight{$sel})`, "in-flight"},
+			{`sum(tct_receiver_hung_requests{$sel})`, "hung"},
+		}},
+		{"Fault states", "short", []query{
+			{`max(tct_receiver_outage_state{$sel})`, "outage"},
+			{`max(tct_receiver_burst_state{$sel})`, "burst"},
+			{`max(tct_receiver_cert_fault_state{$sel})`, "cert fault"},
+			{`min(tct_receiver_keepalive_state{$sel})`, "keep-alive"},
+		}},
+		{"Shed requests by reason", "reqps", []query{
+			{`sum by (reason) (rate(tct_receiver_shed_total{$sel}[$__rate_interval]))`, "{{reason}}"},
+		}},
+		{"Request size", "bytes", quantiles("tct_receiver_request_size_bytes")},
+		{"SLO burn rate", "short", []query{
+			{`max by (window) (tct_receiver_slo_burn_rate{$sel})`, "{{window}}"},
+		}},
+		{"Active phases", "short", []query{
+			{`max by (source, phase) (tct_receiver_phase{$sel})`, "{{source}}: {{phase}}"},
+		}},
+	},
+	"echo": {
+		{"Events by outcome", "ops", []query{
+			{`sum by (outcome) (rate(tct_echo_events_total{$sel}[$__rate_interval]))`, "{{outcome}}"},
+		}},
+		{"Throughput", "Bps", []query{
+			{`sum(rate(tct_echo_bytes_total{$sel}[$__rate_interval]))`, "bytes"},
+		}},
+		{"Connections", "short", []query{
+			{`sum(tct_echo_connections{$sel})`, "connections"},
+		}},
+	},
+}
+
+// quantiles returns the p50, p90, and p99 queries of a histogram.
+func quantiles(histogram string) []query {
+	var qs []query
+	for _, p := range []int{50, 90, 99} {
+		qs = append(qs, query{
+			expr:   fmt.Sprintf(`histogram_quantile(%g, sum by (le) (rate(%s_bucket{$sel}[$__rate_interval])))`, float64(p)/100, histogram),
+			legend: fmt.Sprintf("p%d", p),
+		})
+	}
+	return qs
+}
+
+// Modes lists the modes with panels, in dashboard order.
+var Modes = []string{"sender", "receiver", "echo"}
+
+// Generate returns the dashboard JSON with a row of panels per mode for
+// metrics named with the namespace prefix. An empty datasource makes the
+// Prometheus data source an input chosen on import; otherwise it is the UID
+// of the data source to use.
+func Generate(modes []string, datasource, namespace string) ([]byte, error) {
+	ds := map[string]string{"type": "prometheus", "uid": datasource}
+	if datasource == "" {
+		ds["uid"] = datasourceInput
+	}
+
+	var panels []map[string]any
+	id, y := 1, 0
+	for _, mode := range modes {
+		ps, ok := rows[mode]
+		if !ok {
+			return nil, fmt.Errorf("invalid mode %q (must be one of %s)", mode, strings.Join(Modes, ", "))
+		}
+		panels = append(panels, map[string]any{
+			"id":        id,
+			"type":      "row",
+			"title":     strings.ToUpper(mode[:1]) + mode[1:],
+			"collapsed": false,
+			"gridPos":   map[string]int{"h": 1, "w": 24, "x": 0, "y": y},
+			"panels":    []any{},
+		})
+		id, y = id+1, y+1
+
+		for i, p := range ps {
+			var targets []map[string]any
+			for j, q := range p.queries {
+				targets = append(targets, map[string]any{
+					"datasource":   ds,
+					"expr":         expr(q.expr, namespace),
+					"legendFormat": q.legend,
+					"refId":        string(rune('A' + j)),
+				})
+			}
+			panels = append(panels, map[string]any{
+				"id":         id,
+				"type":       "timeseries",
+				"title":      p.title,
+				"datasource": ds,
+				"gridPos":    map[string]int{"h": 8, "w": 12, "x": 12 * (i % 2), "y": y + 8*(i/2)},
+				"fieldConfig": map[string]any{
+					"defaults":  map[string]any{"unit": p.unit},
+					"overrides": []any{},
+				},
+				"targets": targets,
+			})
+			id++
+		}
+		y += 8 * ((len(ps) + 1) / 2)
+	}
+
+	dashboard := map[string]any{
+		"title":         "tct",
+		"uid":           "tct",
+		"tags":          []string{"tct"},
+		"schemaVersion": 39,
+		"editable":      true,
+		"time":          map[string]string{"from": "now-30m", "to": "now"},
+		"refresh":       "10s",
+		"templating": map[string]any{"list": []any{map[string]any{
+			"name":       "instance",
+			"label":      "Instance",
+			"type":       "query",
+			"datasource": ds,
+			"query":      "label_values(" + namespace + "_build_info, instance)",
+			"refresh":    2,
+			"multi":      true,
+			"includeAll": true,
+			"current":    map[string]any{"text": "All", "value": "$__all"},
+		}}},
+		"panels": panels,
+	}
+	if datasource == "" {
+		dashboard["__inputs"] = []any{map[string]string{
+			"name":       "DS_PROMETHEUS",
+			"label":      "Prometheus",
+			"type":       "datasource",
+			"pluginId":   "prometheus",
+			"pluginName": "Prometheus",
+		}}
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
+
+// expr completes a panel query: the instance selector is filled in and
+// metric names are prefixed with the namespace.
+func expr(q, namespace string) string {
+	q = strings.ReplaceAll(q, "$sel", `instance=~"$instance"`)
+	return strings.ReplaceAll(q, "tct_", namespace+"_")
+}
@@ -0,0 +1,31 @@
+// Package deadline propagates request deadlines between tct instances via
+// a header carrying the remaining time budget in milliseconds.
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header carries the remaining time budget of a request in milliseconds.
+// A relative budget avoids depending on synchronized clocks.
+const Header = "X-TCT-Timeout-Ms"
+
+// Set writes the remaining budget of ctx to h. No-op if ctx has no deadline.
+func Set(ctx context.Context, h http.Header) {
+	if d, ok := ctx.Deadline(); ok {
+		h.Set(Header, strconv.FormatInt(max(time.Until(d).Milliseconds(), 0), 10))
+	}
+}
+
+// FromRequest returns the request context bounded by the budget in the
+// request header, if present and valid.
+func FromRequest(r *http.Request) (context.Context, context.CancelFunc) {
+	ms, err := strconv.ParseInt(r.Header.Get(Header), 10, 64)
+	if err != nil || ms < 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), time.Duration(ms)*time.Millisecond)
+}
@@ -0,0 +1,87 @@
+// Package dedup provides a bounded LRU of idempotency keys used to detect
+// duplicate deliveries on the receiver.
+package dedup
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+)
+
+// Response is the original response recorded for a key, with the headers
+// needed to replay it (e.g. Location of a redirect).
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// entry is a single cache element.
+type entry struct {
+	key  string
+	resp *Response // nil while the original request is in flight
+}
+
+// Cache is a fixed-size LRU of seen keys.
+// It is safe for concurrent use.
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front = most recently used
+	items map[string]*list.Element
+}
+
+// New creates a cache holding up to size keys.
+func New(size int) *Cache {
+	return &Cache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// Seen reports whether key was seen before and returns the recorded response,
+// if any. Unseen keys are added, evicting the least recently used key when
+// the cache is full.
+func (c *Cache) Seen(key string) (bool, *Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return true, el.Value.(*entry).resp
+	}
+
+	c.items[key] = c.order.PushFront(&entry{key: key})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+
+	return false, nil
+}
+
+// Store records the response for a previously seen key.
+// Keys evicted in the meantime are ignored.
+func (c *Cache) Store(key string, status int, header http.Header, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*entry).resp = &Response{Status: status, Header: header, Body: body}
+	}
+}
+
+// Release removes key if no response was recorded for it, so retries of a
+// request aborted without response are not treated as duplicates of a
+// request in flight forever.
+func (c *Cache) Release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok && el.Value.(*entry).resp == nil {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+}
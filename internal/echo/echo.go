@@ -0,0 +1,177 @@
+// Package echo provides the L4 echo listener for echo mode.
+package echo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/metrics"
+	"github.com/neox5/tct/internal/random"
+)
+
+// bufferSize is the maximum number of bytes handled per read or datagram.
+const bufferSize = 64 * 1024
+
+// Run starts the echo listener for the configured protocol.
+// It blocks until the context is cancelled or the listener fails.
+func Run(ctx context.Context, cfg *config.Config, log *logger.Logger, m *metrics.EchoMetrics) error {
+	addr := net.JoinHostPort(strings.Trim(cfg.BindAddr, "[]"), strconv.Itoa(cfg.EchoPort))
+	rng := random.New(cfg.Seed, "echo")
+
+	switch cfg.EchoProtocol {
+	case "tcp":
+		return runTCP(ctx, addr, cfg, log, m, rng)
+	case "udp":
+		return runUDP(ctx, addr, cfg, log, m, rng)
+	default:
+		return fmt.Errorf("unsupported echo protocol %q", cfg.EchoProtocol)
+	}
+}
+
+// runTCP accepts TCP connections and echoes received bytes per connection.
+func runTCP(ctx context.Context, addr string, cfg *config.Config, log *logger.Logger, m *metrics.EchoMetrics, rng *random.Rand) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("echo listen error: %w", err)
+	}
+
+	// Close listener on shutdown to unblock Accept
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	log.Info("starting echo listener", "protocol", "tcp", "addr", addr)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("echo accept error: %w", err)
+		}
+		go handleConn(ctx, conn, cfg, log, m, rng)
+	}
+}
+
+// handleConn echoes bytes on a single TCP connection, applying delay,
+// drop, and reset decisions per read.
+func handleConn(ctx context.Context, conn net.Conn, cfg *config.Config, log *logger.Logger, m *metrics.EchoMetrics, rng *random.Rand) {
+	m.ConnectionsInc()
+	defer m.ConnectionsDec()
+	defer conn.Close()
+
+	// Close connection on shutdown to unblock Read
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	buf := make([]byte, bufferSize)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			// 1. Apply reset decision (RST instead of FIN)
+			if rng.Float64() < cfg.EchoResetRate {
+				m.RecordEvent("reset")
+				log.Debug("resetting connection", "remote", conn.RemoteAddr())
+				if tcp, ok := conn.(*net.TCPConn); ok {
+					tcp.SetLinger(0)
+				}
+				return
+			}
+
+			// 2. Apply drop decision (discard bytes silently)
+			if rng.Float64() < cfg.EchoDropRate {
+				m.RecordEvent("dropped")
+				continue
+			}
+
+			// 3. Apply delay and echo
+			if !sleep(ctx, cfg.EchoDelay) {
+				return
+			}
+			if _, werr := conn.Write(buf[:n]); werr != nil {
+				log.Debug("echo write error", "remote", conn.RemoteAddr(), "error", werr)
+				return
+			}
+			m.RecordEvent("echoed")
+			m.AddBytes(n)
+		}
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) && ctx.Err() == nil {
+				log.Debug("connection closed", "remote", conn.RemoteAddr(), "error", err)
+			}
+			return
+		}
+	}
+}
+
+// runUDP receives datagrams and echoes them back to the sender.
+// Reset decisions do not apply to UDP.
+func runUDP(ctx context.Context, addr string, cfg *config.Config, log *logger.Logger, m *metrics.EchoMetrics, rng *random.Rand) error {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("echo listen error: %w", err)
+	}
+
+	// Close socket on shutdown to unblock ReadFrom
+	go func() {
+		<-ctx.Done()
+		pc.Close()
+	}()
+
+	log.Info("starting echo listener", "protocol", "udp", "addr", addr)
+
+	buf := make([]byte, bufferSize)
+	for {
+		n, remote, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("echo read error: %w", err)
+		}
+
+		// 1. Apply drop decision (no reply)
+		if rng.Float64() < cfg.EchoDropRate {
+			m.RecordEvent("dropped")
+			continue
+		}
+
+		// 2. Apply delay and echo without blocking the read loop
+		datagram := make([]byte, n)
+		copy(datagram, buf[:n])
+		go func() {
+			if !sleep(ctx, cfg.EchoDelay) {
+				return
+			}
+			if _, err := pc.WriteTo(datagram, remote); err != nil {
+				log.Debug("echo write error", "remote", remote, "error", err)
+				return
+			}
+			m.RecordEvent("echoed")
+			m.AddBytes(len(datagram))
+		}()
+	}
+}
+
+// sleep waits for the given duration or until the context is cancelled.
+// Returns false if the context was cancelled.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -3,9 +3,16 @@
 package env
 
 import (
+	"encoding"
+	"errors"
+	"flag"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,16 +24,395 @@ type tagOptions struct {
 	defaultVal string
 	min        string
 	max        string
+	oneof      []string
+	pattern    string
+	fromFile   bool
+	secret     bool
+	scheme     []string
+	requiredIf string
+	minLen     string
+	maxLen     string
+	aliases    []string // deprecated keys of renamed variables
+}
+
+// hasConstraints reports whether any value constraint is set.
+func (o tagOptions) hasConstraints() bool {
+	return o.min != "" || o.max != "" || len(o.oneof) > 0 || o.pattern != "" ||
+		len(o.scheme) > 0 || o.minLen != "" || o.maxLen != ""
+}
+
+// Lookup returns the value for a key and whether it is set.
+type Lookup func(key string) (string, bool)
+
+// Chain returns a lookup that consults lookups in order and returns the
+// first value found, so earlier sources take precedence.
+func Chain(lookups ...Lookup) Lookup {
+	return func(key string) (string, bool) {
+		for _, lookup := range lookups {
+			if v, ok := lookup(key); ok {
+				return v, true
+			}
+		}
+		return "", false
+	}
+}
+
+// Keys returns the environment keys (including aliases) of all tagged fields
+// of the struct pointed to by cfg, in field order.
+func Keys(cfg any) []string {
+	var keys []string
+	for _, field := range taggedFields(reflect.TypeOf(cfg).Elem(), "") {
+		keys = append(keys, field.key)
+		for _, alias := range field.opts.aliases {
+			keys = append(keys, field.prefix+alias)
+		}
+		if field.opts.fromFile {
+			keys = append(keys, field.key+fileSuffix)
+		}
+	}
+	return keys
+}
+
+// Redacted replaces the values of secret fields returned by Values.
+const Redacted = "[redacted]"
+
+// Values returns the current values of all tagged fields of the struct
+// pointed to by cfg, keyed by environment key, in the format they are parsed
+// from. Non-empty values of fields tagged secret are replaced by Redacted.
+func Values(cfg any) map[string]string {
+	values := map[string]string{}
+	collectValues(reflect.ValueOf(cfg).Elem(), "", values, true)
+	return values
+}
+
+// collectValues adds the formatted values of the tagged fields of v to values,
+// replacing the values of secret fields if redact is set.
+func collectValues(v reflect.Value, prefix string, values map[string]string, redact bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if nested, ok := nestedPrefix(field); ok {
+			collectValues(v.Field(i), prefix+nested, values, redact)
+			continue
+		}
+		tag := field.Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		key, opts := parseTag(tag)
+		value := formatValue(v.Field(i))
+		if redact && opts.secret && value != "" {
+			value = Redacted
+		}
+		values[prefix+key] = value
+	}
+}
+
+// formatValue formats a field value the way setField parses it.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return ""
+	}
+	if v.Type() == urlType && v.CanAddr() {
+		return v.Addr().Interface().(*url.URL).String()
+	}
+	if v.CanInterface() {
+		switch u := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			text, err := u.MarshalText()
+			if err == nil {
+				return string(text)
+			}
+		case fmt.Stringer:
+			return u.String()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		return formatValue(v.Elem())
+
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(items, ",")
+
+	case reflect.Map:
+		var items []string
+		iter := v.MapRange()
+		for iter.Next() {
+			items = append(items, formatValue(iter.Key())+"="+formatValue(iter.Value()))
+		}
+		slices.Sort(items)
+		return strings.Join(items, ",")
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// Field describes a tagged field, e.g. for generated documentation.
+type Field struct {
+	Key         string   `json:"key"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+	Constraints []string `json:"constraints,omitempty"`
+}
+
+// Describe returns descriptions of all tagged fields of the struct pointed
+// to by cfg, in field order.
+func Describe(cfg any) []Field {
+	var fields []Field
+	for _, field := range taggedFields(reflect.TypeOf(cfg).Elem(), "") {
+		opts := field.opts
+		var constraints []string
+		add := func(name, value string) {
+			if value != "" {
+				constraints = append(constraints, name+"="+value)
+			}
+		}
+		add("min", opts.min)
+		add("max", opts.max)
+		add("minlen", opts.minLen)
+		add("maxlen", opts.maxLen)
+		add("oneof", strings.Join(opts.oneof, "|"))
+		add("pattern", opts.pattern)
+		add("scheme", strings.Join(opts.scheme, "|"))
+		if opts.requiredIf != "" {
+			add("required_if", field.prefix+opts.requiredIf)
+		}
+		if opts.fromFile {
+			constraints = append(constraints, "fromFile")
+		}
+		if opts.secret {
+			constraints = append(constraints, "secret")
+		}
+
+		var aliases []string
+		for _, alias := range opts.aliases {
+			aliases = append(aliases, field.prefix+alias)
+		}
+		fields = append(fields, Field{
+			Key:         field.key,
+			Aliases:     aliases,
+			Type:        typeName(field.typ),
+			Default:     opts.defaultVal,
+			Required:    opts.required,
+			Constraints: constraints,
+		})
+	}
+	return fields
+}
+
+// typeName returns a readable name of a field type for documentation.
+func typeName(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		return "duration"
+	case reflect.TypeOf(ByteSize(0)):
+		return "byte size"
+	case reflect.TypeOf(Schedule(nil)):
+		return "schedule"
+	case urlType:
+		return "url"
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeName(t.Elem())
+	case reflect.Slice:
+		return "list of " + typeName(t.Elem())
+	case reflect.Map:
+		return "map of " + typeName(t.Key()) + " to " + typeName(t.Elem())
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	}
+	return t.Kind().String()
+}
+
+// Aliases returns the deprecated aliases of the tagged fields of the struct
+// pointed to by cfg, mapped to the current keys.
+func Aliases(cfg any) map[string]string {
+	aliases := map[string]string{}
+	for _, field := range taggedFields(reflect.TypeOf(cfg).Elem(), "") {
+		for _, alias := range field.opts.aliases {
+			aliases[field.prefix+alias] = field.key
+		}
+	}
+	return aliases
+}
+
+// Unknown returns the names of environment variables starting with prefix
+// that are not bound to any tagged field of the struct pointed to by cfg nor
+// listed in extra, sorted. It catches misspelled variables, which would
+// otherwise be silently ignored.
+func Unknown(cfg any, prefix string, extra ...string) []string {
+	known := map[string]bool{}
+	for _, key := range append(Keys(cfg), extra...) {
+		known[key] = true
+	}
+
+	var unknown []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(key, prefix) && !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+// Flags registers one command-line flag per tagged field of the struct
+// pointed to by cfg. Flag names are the environment keys without prefix in
+// lower kebab case (TCT_ERROR_RATE with prefix "TCT_" becomes -error-rate).
+// The returned lookup yields the values of flags set on the command line,
+// keyed by environment key; values are converted and validated by ParseWith.
+func Flags(fs *flag.FlagSet, cfg any, prefix string) Lookup {
+	values := map[string]string{}
+	for _, field := range taggedFields(reflect.TypeOf(cfg).Elem(), "") {
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(field.key, prefix)), "_", "-")
+		usage := "sets " + field.key
+		if field.opts.defaultVal != "" {
+			usage += fmt.Sprintf(" (default %s)", field.opts.defaultVal)
+		}
+		fs.Var(&flagValue{key: field.key, isBool: isBool(field.typ), values: values}, name, usage)
+		if field.opts.fromFile {
+			fs.Var(&flagValue{key: field.key + fileSuffix, values: values}, name+"-file", "sets "+field.key+fileSuffix)
+		}
+	}
+
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+// isBool reports whether t is a bool or a pointer to one.
+func isBool(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// flagValue records a flag value under its environment key.
+type flagValue struct {
+	key    string
+	isBool bool
+	values map[string]string
+}
+
+// String returns the recorded value. Implements flag.Value.
+func (f *flagValue) String() string {
+	if f == nil || f.values == nil {
+		return ""
+	}
+	return f.values[f.key]
+}
+
+// Set records the value. Implements flag.Value.
+func (f *flagValue) Set(v string) error {
+	f.values[f.key] = v
+	return nil
+}
+
+// IsBoolFlag allows boolean flags without a value (e.g. -http2).
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// taggedField is an env-tagged struct field with its fully prefixed key.
+type taggedField struct {
+	key    string
+	prefix string
+	opts   tagOptions
+	typ    reflect.Type
+}
+
+// taggedFields returns all env-tagged fields of t, descending into embedded
+// and envPrefix-tagged structs, in field order. Keys are prefixed with prefix.
+func taggedFields(t reflect.Type, prefix string) []taggedField {
+	var fields []taggedField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if nested, ok := nestedPrefix(field); ok {
+			fields = append(fields, taggedFields(field.Type, prefix+nested)...)
+			continue
+		}
+		if tag := field.Tag.Get("env"); tag != "" {
+			key, opts := parseTag(tag)
+			fields = append(fields, taggedField{key: prefix + key, prefix: prefix, opts: opts, typ: field.Type})
+		}
+	}
+	return fields
+}
+
+// nestedPrefix reports whether the field is a struct whose fields are parsed
+// in place: embedded structs (no prefix) and structs with an envPrefix tag.
+func nestedPrefix(field reflect.StructField) (string, bool) {
+	if field.Type.Kind() != reflect.Struct {
+		return "", false
+	}
+	if field.Anonymous {
+		return "", true
+	}
+	return field.Tag.Lookup("envPrefix")
 }
 
 // Parse loads configuration from environment variables into the provided struct.
 // The struct must be passed as a pointer.
 //
+// Renamed variables keep working through aliases listed after the key
+// (env:"TCT_RATE|TCT_RPS"); the key takes precedence over its aliases.
+//
 // Supported tags:
 //   - required: Field must have a value set in environment
+//   - required_if=<KEY>=<value>: Field must have a non-zero value if the
+//     field with key KEY (relative to the envPrefix of the field) has the
+//     given value; KEY!=<value> negates the condition, so KEY!= requires
+//     the field whenever KEY is set
 //   - default=<value>: Default value if environment variable not set
-//   - min=<value>: Minimum allowed value (numeric types and durations)
-//   - max=<value>: Maximum allowed value (numeric types and durations)
+//   - min=<value>: Minimum allowed value (numeric types, durations, and
+//     byte sizes)
+//   - max=<value>: Maximum allowed value (numeric types, durations, and
+//     byte sizes)
+//   - minlen=<n>: Minimum length of strings, number of slice elements or
+//     map entries
+//   - maxlen=<n>: Maximum length of strings, number of slice elements or
+//     map entries
+//   - oneof=<a>|<b>|<c>: Value must be one of the listed values
+//   - pattern=<regexp>: String value must fully match the regular expression
+//     (must be the last option if it contains commas)
+//   - scheme=<a>|<b>: URL must use one of the listed schemes
+//   - secret: Value is redacted by Values (e.g. credentials)
+//   - fromFile: If the variable is unset, read the value from the file
+//     named by <KEY>_FILE (e.g. a mounted secret); a trailing newline is
+//     stripped
+//
+// Values and defaults may reference other variables as ${NAME}, which is
+// replaced by the value of NAME (empty if unset); values read from files
+// are used verbatim.
+//
+// Nested struct fields tagged with envPrefix:"PREFIX_" are parsed in place
+// with their keys prefixed, so related fields can be grouped without
+// renaming their variables.
+//
+// Types implementing encoding.TextUnmarshaler or flag.Value (e.g. net.IP,
+// slog.Level) are parsed by their own methods; pointers to supported types
+// are allocated as needed. Pointer fields without a default stay nil while
+// unset, so an unset setting can be told apart from an explicit zero value
+// (e.g. *float64 for a rate where 0 is meaningful). url.URL fields must
+// hold absolute URLs; a missing port is filled in from the scheme (80 for
+// http, 443 for https).
+//
+// Slices of supported types are parsed from comma-separated values
+// (e.g. "a,b,c") and maps from comma-separated key=value pairs
+// (e.g. "a=1,b=2") with unique keys; min and max apply to each element or
+// map value.
 //
 // Example:
 //
@@ -34,26 +420,117 @@ type tagOptions struct {
 //	    Port int `env:"PORT,default=8080,min=1,max=65535"`
 //	}
 func Parse(cfg any) error {
+	return ParseWith(cfg, os.LookupEnv)
+}
+
+// ParseWith is like Parse but reads values through lookup instead of the
+// process environment.
+//
+// All fields are parsed even if some fail, and required_if conditions and
+// Validate methods are checked as well unless they involve a field that
+// failed to parse; the returned error joins all errors (see errors.Join).
+func ParseWith(cfg any, lookup Lookup) error {
 	v := reflect.ValueOf(cfg)
 	if v.Kind() != reflect.Pointer || v.IsNil() {
 		return fmt.Errorf("config must be a non-nil pointer")
 	}
 
-	return parseStruct(v.Elem())
+	failed := map[string]bool{}
+	parseErr := parseStruct(v.Elem(), lookup, "", failed)
+	return errors.Join(parseErr, checkRequiredIf(v.Elem(), failed), validateStruct(v.Elem(), "", failed))
+}
+
+// Validator is implemented by config structs with invariants spanning
+// several fields. Parse calls Validate after all fields are parsed, on nested
+// structs before the structs containing them.
+type Validator interface {
+	Validate() error
 }
 
-// parseStruct recursively parses struct fields.
-func parseStruct(v reflect.Value) error {
+// validateStruct calls the Validate methods of v and its nested structs,
+// skipping those of structs with a field (keyed with prefix) in failed.
+func validateStruct(v reflect.Value, prefix string, failed map[string]bool) error {
+	var errs []error
 	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if nested, ok := nestedPrefix(t.Field(i)); ok {
+			if err := validateStruct(v.Field(i), prefix+nested, failed); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	for _, field := range taggedFields(t, prefix) {
+		if failed[field.key] {
+			return nil
+		}
+	}
+
+	if val, ok := v.Addr().Interface().(Validator); ok {
+		return val.Validate()
+	}
+	return nil
+}
+
+// checkRequiredIf returns the joined errors of all fields whose required_if
+// condition holds but that have a zero value. Fields and conditions on
+// fields in failed are not checked.
+func checkRequiredIf(v reflect.Value, failed map[string]bool) error {
+	values := map[string]string{}
+	collectValues(v, "", values, false)
+
+	var errs []error
+	for _, field := range taggedFields(v.Type(), "") {
+		if field.opts.requiredIf == "" {
+			continue
+		}
+		key, want, ok := strings.Cut(field.opts.requiredIf, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: invalid required_if %q (must be 'KEY=value' or 'KEY!=value')", field.key, field.opts.requiredIf))
+			continue
+		}
+		negate := strings.HasSuffix(key, "!")
+		key = field.prefix + strings.TrimSuffix(key, "!")
+		if failed[field.key] || failed[key] {
+			continue
+		}
+
+		if (values[key] == want) == negate {
+			continue
+		}
+		if values[field.key] != formatValue(reflect.Zero(field.typ)) {
+			continue
+		}
+		switch {
+		case negate && want == "":
+			errs = append(errs, fmt.Errorf("%s is required when %s is set", field.key, key))
+		case negate:
+			errs = append(errs, fmt.Errorf("%s is required when %s is not %s", field.key, key, want))
+		default:
+			errs = append(errs, fmt.Errorf("%s is required when %s is %s", field.key, key, want))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseStruct recursively parses struct fields, prefixing keys with prefix.
+// Errors are collected per field and returned joined; the keys of the
+// fields that failed are added to failed.
+func parseStruct(v reflect.Value, lookup Lookup, prefix string, failed map[string]bool) error {
+	t := v.Type()
+	var errs []error
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		fieldVal := v.Field(i)
 
-		// Handle embedded structs (e.g., CommonConfig)
-		if field.Anonymous {
-			if err := parseStruct(fieldVal); err != nil {
-				return err
+		// Handle embedded structs (e.g., CommonConfig) and nested structs
+		// with an envPrefix tag (e.g., Outage with prefix TCT_OUTAGE_)
+		if nested, ok := nestedPrefix(field); ok {
+			if err := parseStruct(fieldVal, lookup, prefix+nested, failed); err != nil {
+				errs = append(errs, err)
 			}
 			continue
 		}
@@ -66,17 +543,25 @@ func parseStruct(v reflect.Value) error {
 
 		// Parse tag options
 		envKey, opts := parseTag(tag)
+		envKey = prefix + envKey
 
-		// Get value from environment
-		envVal, exists := os.LookupEnv(envKey)
+		// Get value from environment (or the file it references)
+		envVal, exists, err := lookupValue(lookup, envKey, prefix, opts)
+		if err != nil {
+			errs = append(errs, err)
+			failed[envKey] = true
+			continue
+		}
 
 		// Handle required/default
 		if !exists {
 			if opts.required {
-				return fmt.Errorf("%s is required", envKey)
+				errs = append(errs, fmt.Errorf("%s is required", envKey))
+				failed[envKey] = true
+				continue
 			}
 			if opts.defaultVal != "" {
-				envVal = opts.defaultVal
+				envVal = expand(opts.defaultVal, lookup)
 			} else {
 				continue // Skip unset optional fields without defaults
 			}
@@ -84,47 +569,188 @@ func parseStruct(v reflect.Value) error {
 
 		// Parse and set field value
 		if err := setField(fieldVal, envVal, envKey); err != nil {
-			return err
+			errs = append(errs, err)
+			failed[envKey] = true
+			continue
 		}
 
 		// Validate constraints
 		if err := validateField(fieldVal, opts, envKey); err != nil {
-			return err
+			errs = append(errs, err)
+			failed[envKey] = true
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// fileSuffix is appended to keys of fromFile fields to name the variable
+// holding the path of the file with the value.
+const fileSuffix = "_FILE"
+
+// lookupValue returns the value for envKey or, if unset, its first set
+// alias (prefixed like envKey). Fields with the fromFile option fall back to
+// reading the file named by envKey_FILE if neither is set.
+func lookupValue(lookup Lookup, envKey, prefix string, opts tagOptions) (string, bool, error) {
+	v, ok := lookup(envKey)
+	for _, alias := range opts.aliases {
+		if ok {
+			break
+		}
+		v, ok = lookup(prefix + alias)
+	}
+	if ok || !opts.fromFile {
+		return expand(v, lookup), ok, nil
+	}
+	path, ok := lookup(envKey + fileSuffix)
+	if !ok {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s%s: %w", envKey, fileSuffix, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// varRef matches ${NAME} references to other variables.
+var varRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expand replaces ${NAME} references in value with the value of NAME from
+// lookup (empty if unset). Other uses of $ are kept as is.
+func expand(value string, lookup Lookup) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+	return varRef.ReplaceAllStringFunc(value, func(ref string) string {
+		v, _ := lookup(ref[2 : len(ref)-1])
+		return v
+	})
 }
 
 // parseTag parses an env tag string into key and options.
-// Format: "ENV_KEY,option1,option2=value"
+// Format: "ENV_KEY,option1,option2=value"; "ENV_KEY|OLD_KEY" lists
+// deprecated aliases after the key.
 func parseTag(tag string) (envKey string, opts tagOptions) {
 	parts := strings.Split(tag, ",")
-	envKey = parts[0]
+	keys := strings.Split(parts[0], "|")
+	envKey, opts.aliases = keys[0], keys[1:]
 
+	// cont receives unrecognized parts continuing a value containing commas
+	var cont *string
 	for _, part := range parts[1:] {
 		switch {
 		case part == "required":
 			opts.required = true
+		case strings.HasPrefix(part, "required_if="):
+			opts.requiredIf = strings.TrimPrefix(part, "required_if=")
+		case part == "fromFile":
+			opts.fromFile = true
+		case part == "secret":
+			opts.secret = true
 		case strings.HasPrefix(part, "default="):
 			opts.defaultVal = strings.TrimPrefix(part, "default=")
+			cont = &opts.defaultVal
+			continue
+		case strings.HasPrefix(part, "pattern="):
+			opts.pattern = strings.TrimPrefix(part, "pattern=")
+			cont = &opts.pattern
+			continue
+		case strings.HasPrefix(part, "minlen="):
+			opts.minLen = strings.TrimPrefix(part, "minlen=")
+		case strings.HasPrefix(part, "maxlen="):
+			opts.maxLen = strings.TrimPrefix(part, "maxlen=")
 		case strings.HasPrefix(part, "min="):
 			opts.min = strings.TrimPrefix(part, "min=")
 		case strings.HasPrefix(part, "max="):
 			opts.max = strings.TrimPrefix(part, "max=")
+		case strings.HasPrefix(part, "scheme="):
+			opts.scheme = strings.Split(strings.TrimPrefix(part, "scheme="), "|")
+		case strings.HasPrefix(part, "oneof="):
+			opts.oneof = strings.Split(strings.TrimPrefix(part, "oneof="), "|")
+		case cont != nil:
+			// Unrecognized parts continue a comma-separated slice default
+			// or a pattern containing commas
+			*cont += "," + part
+			continue
 		}
+		cont = nil
 	}
 
 	return envKey, opts
 }
 
 // setField converts the string value to the appropriate type and sets the field.
+// Types implementing encoding.TextUnmarshaler or flag.Value parse themselves.
 func setField(field reflect.Value, value string, envKey string) error {
+	if field.CanAddr() {
+		switch u := field.Addr().Interface().(type) {
+		case encoding.TextUnmarshaler:
+			if err := u.UnmarshalText([]byte(value)); err != nil {
+				return fmt.Errorf("%s: invalid value %q: %w", envKey, value, err)
+			}
+			return nil
+		case flag.Value:
+			if err := u.Set(value); err != nil {
+				return fmt.Errorf("%s: invalid value %q: %w", envKey, value, err)
+			}
+			return nil
+		}
+	}
+
+	if field.Type() == urlType {
+		u, err := parseURL(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid URL %q: %w", envKey, value, err)
+		}
+		field.Set(reflect.ValueOf(*u))
+		return nil
+	}
+
 	switch field.Kind() {
+	case reflect.Pointer:
+		ptr := reflect.New(field.Type().Elem())
+		if err := setField(ptr.Elem(), value, envKey); err != nil {
+			return err
+		}
+		field.Set(ptr)
+
+	case reflect.Slice:
+		items := splitList(value)
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setField(slice.Index(i), item, envKey); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+
+	case reflect.Map:
+		m := reflect.MakeMap(field.Type())
+		for _, item := range splitList(value) {
+			k, v, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("%s: invalid map entry %q (must be 'key=value')", envKey, item)
+			}
+			key := reflect.New(field.Type().Key()).Elem()
+			if err := setField(key, strings.TrimSpace(k), envKey); err != nil {
+				return err
+			}
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setField(elem, strings.TrimSpace(v), envKey); err != nil {
+				return err
+			}
+			if m.MapIndex(key).IsValid() {
+				return fmt.Errorf("%s: duplicate map key %q", envKey, strings.TrimSpace(k))
+			}
+			m.SetMapIndex(key, elem)
+		}
+		field.Set(m)
+
 	case reflect.String:
 		field.SetString(value)
 
-	case reflect.Int, reflect.Int64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		// Handle time.Duration specially
 		if field.Type() == reflect.TypeOf(time.Duration(0)) {
 			d, err := time.ParseDuration(value)
@@ -133,15 +759,22 @@ func setField(field reflect.Value, value string, envKey string) error {
 			}
 			field.SetInt(int64(d))
 		} else {
-			i, err := strconv.ParseInt(value, 10, 64)
+			i, err := strconv.ParseInt(value, 10, field.Type().Bits())
 			if err != nil {
 				return fmt.Errorf("%s: invalid integer %q: %w", envKey, value, err)
 			}
 			field.SetInt(i)
 		}
 
-	case reflect.Float64:
-		f, err := strconv.ParseFloat(value, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: invalid unsigned integer %q: %w", envKey, value, err)
+		}
+		field.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
 		if err != nil {
 			return fmt.Errorf("%s: invalid float %q: %w", envKey, value, err)
 		}
@@ -161,15 +794,106 @@ func setField(field reflect.Value, value string, envKey string) error {
 	return nil
 }
 
-// validateField validates field value against min/max constraints.
+// urlType is the type of URL fields.
+var urlType = reflect.TypeOf(url.URL{})
+
+// defaultPorts maps URL schemes to the port used if a URL has none.
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// parseURL parses an absolute URL and fills in the default port of its scheme.
+func parseURL(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("must be absolute (scheme://host)")
+	}
+	if port, ok := defaultPorts[u.Scheme]; ok && u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u, nil
+}
+
+// splitList splits a comma-separated value into trimmed, non-empty items.
+func splitList(value string) []string {
+	var items []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validateField validates field value against min/max/minlen/maxlen/oneof/
+// pattern/scheme constraints.
 func validateField(field reflect.Value, opts tagOptions, envKey string) error {
 	// No constraints to validate
-	if opts.min == "" && opts.max == "" {
+	if !opts.hasConstraints() {
 		return nil
 	}
 
+	// Validate the value pointed to
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return nil
+		}
+		return validateField(field.Elem(), opts, envKey)
+	}
+
+	// Validate URL schemes
+	if field.Type() == urlType {
+		u := field.Addr().Interface().(*url.URL)
+		if len(opts.scheme) > 0 && !slices.Contains(opts.scheme, u.Scheme) {
+			return fmt.Errorf("%s: invalid URL scheme %q (must be %s)", envKey, u.Scheme, quoteList(opts.scheme))
+		}
+		return nil
+	}
+
+	// Validate enumerated values (elements of slices and maps are checked below)
+	if k := field.Kind(); len(opts.oneof) > 0 && k != reflect.Slice && k != reflect.Map {
+		if v := fmt.Sprint(field.Interface()); !slices.Contains(opts.oneof, v) {
+			return fmt.Errorf("%s: invalid value %q (must be %s)", envKey, v, quoteList(opts.oneof))
+		}
+	}
+
+	// Validate lengths (elements of slices and maps are not length-checked)
+	if k := field.Kind(); k == reflect.String || k == reflect.Slice || k == reflect.Map {
+		if err := validateLen(field.Len(), opts, envKey); err != nil {
+			return err
+		}
+		opts.minLen, opts.maxLen = "", ""
+	}
+
 	switch field.Kind() {
-	case reflect.Int, reflect.Int64:
+	case reflect.String:
+		if opts.pattern != "" {
+			re, err := regexp.Compile("^(?:" + opts.pattern + ")$")
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %w", envKey, opts.pattern, err)
+			}
+			if !re.MatchString(field.String()) {
+				return fmt.Errorf("%s: invalid value %q (must match %s)", envKey, field.String(), opts.pattern)
+			}
+		}
+
+	case reflect.Slice:
+		for i := 0; i < field.Len(); i++ {
+			if err := validateField(field.Index(i), opts, envKey); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		iter := field.MapRange()
+		for iter.Next() {
+			if err := validateField(iter.Value(), opts, envKey); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		// Handle time.Duration
 		if field.Type() == reflect.TypeOf(time.Duration(0)) {
 			d := time.Duration(field.Int())
@@ -194,11 +918,11 @@ func validateField(field reflect.Value, opts tagOptions, envKey string) error {
 				}
 			}
 		} else {
-			// Regular integer
+			// Regular integer (bounds of byte sizes may have units)
 			i := field.Int()
 
 			if opts.min != "" {
-				minVal, err := strconv.ParseInt(opts.min, 10, 64)
+				minVal, err := parseIntBound(field.Type(), opts.min)
 				if err != nil {
 					return fmt.Errorf("%s: invalid min value %q", envKey, opts.min)
 				}
@@ -208,7 +932,7 @@ func validateField(field reflect.Value, opts tagOptions, envKey string) error {
 			}
 
 			if opts.max != "" {
-				maxVal, err := strconv.ParseInt(opts.max, 10, 64)
+				maxVal, err := parseIntBound(field.Type(), opts.max)
 				if err != nil {
 					return fmt.Errorf("%s: invalid max value %q", envKey, opts.max)
 				}
@@ -218,7 +942,30 @@ func validateField(field reflect.Value, opts tagOptions, envKey string) error {
 			}
 		}
 
-	case reflect.Float64:
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := field.Uint()
+
+		if opts.min != "" {
+			minVal, err := strconv.ParseUint(opts.min, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s: invalid min value %q", envKey, opts.min)
+			}
+			if u < minVal {
+				return fmt.Errorf("%s: must be >= %v, got %v", envKey, minVal, u)
+			}
+		}
+
+		if opts.max != "" {
+			maxVal, err := strconv.ParseUint(opts.max, 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s: invalid max value %q", envKey, opts.max)
+			}
+			if u > maxVal {
+				return fmt.Errorf("%s: must be <= %v, got %v", envKey, maxVal, u)
+			}
+		}
+
+	case reflect.Float32, reflect.Float64:
 		f := field.Float()
 
 		if opts.min != "" {
@@ -244,3 +991,49 @@ func validateField(field reflect.Value, opts tagOptions, envKey string) error {
 
 	return nil
 }
+
+// validateLen validates a length against minlen/maxlen constraints.
+func validateLen(n int, opts tagOptions, envKey string) error {
+	if opts.minLen != "" {
+		minLen, err := strconv.Atoi(opts.minLen)
+		if err != nil {
+			return fmt.Errorf("%s: invalid minlen %q", envKey, opts.minLen)
+		}
+		if n < minLen {
+			return fmt.Errorf("%s: length must be >= %d, got %d", envKey, minLen, n)
+		}
+	}
+
+	if opts.maxLen != "" {
+		maxLen, err := strconv.Atoi(opts.maxLen)
+		if err != nil {
+			return fmt.Errorf("%s: invalid maxlen %q", envKey, opts.maxLen)
+		}
+		if n > maxLen {
+			return fmt.Errorf("%s: length must be <= %d, got %d", envKey, maxLen, n)
+		}
+	}
+
+	return nil
+}
+
+// parseIntBound parses a min or max bound of an integer field of type t.
+func parseIntBound(t reflect.Type, s string) (int64, error) {
+	if t == reflect.TypeOf(ByteSize(0)) {
+		b, err := ParseByteSize(s)
+		return int64(b), err
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// quoteList formats values as a quoted list, e.g. "'a', 'b', or 'c'".
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + v + "'"
+	}
+	if len(quoted) <= 2 {
+		return strings.Join(quoted, " or ")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + ", or " + quoted[len(quoted)-1]
+}
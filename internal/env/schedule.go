@@ -0,0 +1,81 @@
+package env
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Step is a value held for a duration.
+type Step struct {
+	Duration time.Duration
+	Value    float64
+}
+
+// Schedule is a sequence of steps parsed from a compact literal of
+// comma-separated duration@value steps, e.g. "5m@10,2m@50,30s@200" holds 10
+// for 5 minutes, then 50 for 2 minutes, then 200 for 30 seconds. Durations
+// must be positive and values must not be negative.
+type Schedule []Step
+
+// UnmarshalText parses a schedule literal. Implements encoding.TextUnmarshaler.
+func (s *Schedule) UnmarshalText(text []byte) error {
+	var steps Schedule
+	for _, item := range splitList(string(text)) {
+		d, v, ok := strings.Cut(item, "@")
+		if !ok {
+			return fmt.Errorf("invalid step %q (must be 'duration@value')", item)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid step %q: duration must be positive", item)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || value < 0 {
+			return fmt.Errorf("invalid step %q: value must be a number >= 0", item)
+		}
+		steps = append(steps, Step{Duration: duration, Value: value})
+	}
+	*s = steps
+	return nil
+}
+
+// String formats the schedule as a literal.
+func (s Schedule) String() string {
+	items := make([]string, len(s))
+	for i, step := range s {
+		items[i] = step.Duration.String() + "@" + strconv.FormatFloat(step.Value, 'g', -1, 64)
+	}
+	return strings.Join(items, ",")
+}
+
+// Total returns the combined duration of all steps.
+func (s Schedule) Total() time.Duration {
+	var total time.Duration
+	for _, step := range s {
+		total += step.Duration
+	}
+	return total
+}
+
+// At returns the value of the step in effect after elapsed time and whether
+// a step is in effect (false once the schedule has ended).
+func (s Schedule) At(elapsed time.Duration) (float64, bool) {
+	if i := s.Index(elapsed); i >= 0 {
+		return s[i].Value, true
+	}
+	return 0, false
+}
+
+// Index returns the index of the step in effect after elapsed time, or -1
+// once the schedule has ended.
+func (s Schedule) Index(elapsed time.Duration) int {
+	for i, step := range s {
+		if elapsed < step.Duration {
+			return i
+		}
+		elapsed -= step.Duration
+	}
+	return -1
+}
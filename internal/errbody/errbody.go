@@ -0,0 +1,84 @@
+// Package errbody renders error response bodies, either as the literal
+// message or as a JSON document built from a template.
+package errbody
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+// RequestIDHeader carries the request ID echoed in JSON error bodies.
+const RequestIDHeader = "X-Request-ID"
+
+// DefaultTemplate is used in JSON format when no template is configured.
+const DefaultTemplate = `{"status":{{.Status}},"message":{{json .Message}},"request_id":{{json .RequestID}}}`
+
+// Data is the template input for a JSON error body.
+type Data struct {
+	Status    int
+	Message   string
+	RequestID string
+}
+
+// Renderer renders error bodies in the configured format.
+type Renderer struct {
+	tmpl *template.Template // nil for text format
+}
+
+// New creates a renderer for format "text" or "json". In JSON format the
+// template text is parsed once (DefaultTemplate if empty); the json function
+// encodes a value as a JSON literal.
+func New(format, text string) (*Renderer, error) {
+	switch format {
+	case "text":
+		return &Renderer{}, nil
+	case "json":
+		if text == "" {
+			text = DefaultTemplate
+		}
+		tmpl, err := template.New("error").Funcs(template.FuncMap{"json": jsonLiteral}).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error body template: %w", err)
+		}
+		return &Renderer{tmpl: tmpl}, nil
+	default:
+		return nil, fmt.Errorf("invalid error body format %q (must be 'text' or 'json')", format)
+	}
+}
+
+// Render returns the content type and body for an error response.
+// Falls back to the literal message if the template fails to execute.
+func (rd *Renderer) Render(r *http.Request, status int, message string) (string, []byte) {
+	if rd.tmpl == nil {
+		return "text/plain; charset=utf-8", []byte(message)
+	}
+
+	var buf bytes.Buffer
+	data := Data{Status: status, Message: message, RequestID: requestID(r)}
+	if err := rd.tmpl.Execute(&buf, data); err != nil {
+		return "text/plain; charset=utf-8", []byte(message)
+	}
+	return "application/json", buf.Bytes()
+}
+
+// requestID returns the request ID from the request header, or a new
+// random ID if absent.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		return id
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// jsonLiteral encodes v as a JSON literal for use inside templates.
+func jsonLiteral(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,188 @@
+// Package events provides a bounded in-memory log of experiment events
+// (fault state changes, phase transitions, crashes), optionally delivered
+// to a webhook, so experiment timelines can be reconstructed for reports.
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/neox5/tct/internal/logger"
+)
+
+// Event describes a single experiment event.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"` // e.g. "outage", "scenario", "schedule", "rate_schedule", "crash"
+	Name   string    `json:"name"`
+	Action string    `json:"action"` // "start", "end", or "trigger"
+}
+
+// queueSize is the number of events buffered for webhook delivery.
+const queueSize = 256
+
+// Log is a fixed-size ring of the most recent events. A nil Log ignores
+// events. It is safe for concurrent use.
+type Log struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	full   bool
+	active map[string]bool // phase state by source and name
+
+	log     *logger.Logger
+	webhook *url.URL
+	client  *http.Client
+	queue   chan Event
+	pending sync.WaitGroup
+}
+
+// New creates a log holding up to size events. If webhook is non-nil,
+// events are also POSTed to it as JSON once Run is started.
+func New(size int, webhook *url.URL, log *logger.Logger) *Log {
+	l := &Log{
+		events:  make([]Event, size),
+		active:  map[string]bool{},
+		log:     log,
+		webhook: webhook,
+	}
+	if webhook != nil {
+		l.client = &http.Client{Timeout: 5 * time.Second}
+		l.queue = make(chan Event, queueSize)
+	}
+	return l
+}
+
+// Phase records the start or end of a phase of the source if its state
+// changed, so repeated state updates are logged once.
+func (l *Log) Phase(source, name string, active bool) {
+	if l == nil {
+		return
+	}
+	key := source + "/" + name
+	l.mu.Lock()
+	if l.active[key] == active {
+		l.mu.Unlock()
+		return
+	}
+	l.active[key] = active
+	l.mu.Unlock()
+
+	action := "end"
+	if active {
+		action = "start"
+	}
+	l.Record(source, name, action)
+}
+
+// Record logs an event now.
+func (l *Log) Record(source, name, action string) {
+	if l == nil {
+		return
+	}
+	e := Event{Time: time.Now(), Source: source, Name: name, Action: action}
+
+	l.mu.Lock()
+	l.events[l.next] = e
+	l.next = (l.next + 1) % len(l.events)
+	if l.next == 0 {
+		l.full = true
+	}
+	l.mu.Unlock()
+
+	if l.queue != nil {
+		l.pending.Add(1)
+		select {
+		case l.queue <- e:
+		default:
+			l.pending.Done()
+			l.log.Warn("event webhook queue full, dropping event", "source", source, "name", name, "action", action)
+		}
+	}
+}
+
+// Query returns the events since the given time (all if zero), oldest
+// first.
+func (l *Log) Query(since time.Time) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count, first := l.next, 0
+	if l.full {
+		count, first = len(l.events), l.next
+	}
+
+	result := []Event{}
+	for i := range count {
+		e := l.events[(first+i)%len(l.events)]
+		if e.Time.Before(since) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// Run delivers events to the webhook until the context is cancelled.
+// No-op without webhook.
+func (l *Log) Run(ctx context.Context) {
+	if l.queue == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-l.queue:
+			if err := l.post(ctx, e); err != nil {
+				l.log.Warn("event webhook delivery failed", "error", err)
+			}
+			l.pending.Done()
+		}
+	}
+}
+
+// Flush waits up to timeout for queued events to be delivered, e.g.
+// before the process exits.
+func (l *Log) Flush(timeout time.Duration) {
+	if l == nil || l.queue == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		l.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// post sends an event to the webhook.
+func (l *Log) post(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.webhook.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -3,19 +3,44 @@ package generator
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/neox5/tct/internal/certs"
 	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/deadline"
 	"github.com/neox5/tct/internal/logger"
 	"github.com/neox5/tct/internal/metrics"
+	"github.com/neox5/tct/internal/slo"
+	"github.com/neox5/tct/internal/telemetry"
+	"github.com/neox5/tct/internal/transit"
 )
 
+// errTooManyRedirects is returned by the client when the redirect limit is exceeded.
+var errTooManyRedirects = errors.New("too many redirects")
+
 // Run executes the sender request generation loop.
-// It generates HTTP POST requests at the configured rate until the context is cancelled.
-func Run(ctx context.Context, cfg *config.Config, log *logger.Logger, m *metrics.SenderMetrics) error {
+// It generates HTTP POST requests until the context is cancelled, at the rate
+// returned by rate (e.g. from a scenario phase) or, if rate is nil, the
+// configured rate schedule or rate. A rate of 0 pauses sending. Outcomes
+// are tracked by objective unless it is nil. Once ctx is cancelled no new
+// requests are sent and Run returns after the requests in flight complete
+// (each bounded by the request timeout, or cancelled at once without one).
+func Run(ctx context.Context, cfg *config.Config, log *logger.Logger, m *metrics.SenderMetrics, rate func() float64, objective *slo.Tracker) error {
+
 	// Wait for start delay
 	if cfg.StartDelay > 0 {
 		log.Info("waiting before starting", "delay", cfg.StartDelay)
@@ -26,77 +51,303 @@ func Run(ctx context.Context, cfg *config.Config, log *logger.Logger, m *metrics
 		}
 	}
 
+	// Follow the rate schedule from now on if no rate is given
+	if rate == nil {
+		rate = ScheduleRate(cfg, m, func() float64 { return cfg.RPS })
+	}
+
 	// Create HTTP client
+	tlsConfig, err := clientTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if cfg.HTTP2 {
+		// HTTP/2 only; cleartext connections use prior knowledge (h2c)
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetHTTP2(true)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+	}
 	client := &http.Client{
-		Timeout: cfg.RequestTimeout,
+		Transport:     transport,
+		Timeout:       cfg.RequestTimeout,
+		CheckRedirect: checkRedirect(cfg),
 	}
 
-	// Calculate interval between requests
-	interval := time.Duration(float64(time.Second) / cfg.RPS)
-	ticker := time.NewTicker(interval)
+	// Calculate interval between requests (adjusted when the rate changes)
+	rps := rate()
+	ticker := time.NewTicker(interval(rps))
 	defer ticker.Stop()
 
-	target := fmt.Sprintf("http://%s:%d/inbox", cfg.ReceiverHost, cfg.ReceiverPort)
-	log.Info("starting request generation", "target", target, "rps", cfg.RPS)
+	target := targetURL(cfg)
+	log.Info("starting request generation", "target", target.Redacted(), "rps", rps)
+
+	// Requests outlive ctx so they are not cut short at shutdown
+	reqCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	var inflight sync.WaitGroup
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info("stopping request generation")
+			if cfg.RequestTimeout == 0 {
+				cancel()
+			}
+			inflight.Wait()
 			return ctx.Err()
 
 		case <-ticker.C:
-			go sendRequest(ctx, client, target, log, m)
+			if r := rate(); r != rps {
+				log.Info("request rate changed", "rps", r)
+				rps = r
+				ticker.Reset(interval(rps))
+			}
+			if rps > 0 {
+				m.RecordSpawn()
+				inflight.Add(1)
+				go func() {
+					defer inflight.Done()
+					sendRequest(reqCtx, client, cfg, target, log, m, objective)
+				}()
+			}
+		}
+	}
+}
+
+// ScheduleRate returns a rate function following the configured rate
+// schedule from its first call on. The rate returned by base (e.g. the configured rate,
+// changed on reload) applies without a schedule and once a non-repeating
+// schedule has ended. The step in effect is reported as phase "step-N"
+// (from 1).
+func ScheduleRate(cfg *config.Config, m *metrics.SenderMetrics, base func() float64) func() float64 {
+	var start time.Time
+	current := -1
+	return func() float64 {
+		if start.IsZero() {
+			start = time.Now()
+		}
+		elapsed := time.Since(start)
+		if cfg.RPSRepeat && len(cfg.RPSSchedule) > 0 {
+			elapsed %= cfg.RPSSchedule.Total()
 		}
+		if i := cfg.RPSSchedule.Index(elapsed); i != current {
+			if current >= 0 {
+				m.SetRateStep("step-"+strconv.Itoa(current+1), false)
+			}
+			if i >= 0 {
+				m.SetRateStep("step-"+strconv.Itoa(i+1), true)
+			}
+			current = i
+		}
+		if rps, ok := cfg.RPSSchedule.At(elapsed); ok {
+			return rps
+		}
+		return base()
+	}
+}
+
+// interval returns the time between requests at rps requests per second.
+// While paused (rps 0) the rate is checked every second.
+func interval(rps float64) time.Duration {
+	if rps <= 0 {
+		return time.Second
+	}
+	return time.Duration(float64(time.Second) / rps)
+}
+
+// targetURL returns the URL requests are sent to: the configured target URL
+// (/inbox if it has no path) or the inbox of the configured receiver.
+func targetURL(cfg *config.Config) *url.URL {
+	if cfg.TargetURL != nil {
+		u := *cfg.TargetURL
+		if u.Path == "" {
+			u.Path = "/inbox"
+		}
+		return &u
+	}
+
+	scheme := "http"
+	if cfg.ReceiverTLS {
+		scheme = "https"
+	}
+	return &url.URL{
+		Scheme: scheme,
+		Host:   net.JoinHostPort(strings.Trim(cfg.ReceiverHost, "[]"), strconv.Itoa(cfg.ReceiverPort)),
+		Path:   "/inbox",
 	}
 }
 
-// sendRequest sends a single HTTP POST request and records metrics.
-func sendRequest(ctx context.Context, client *http.Client, target string, log *logger.Logger, m *metrics.SenderMetrics) {
+// sendRequest sends a single HTTP POST request in a client span and records
+// metrics labeled with the target host and port.
+func sendRequest(ctx context.Context, client *http.Client, cfg *config.Config, u *url.URL, log *logger.Logger, m *metrics.SenderMetrics, objective *slo.Tracker) {
+	target, label := u.Redacted(), u.Host
 	m.InflightInc()
 	defer m.InflightDec()
 
 	start := time.Now()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
 	if err != nil {
-		m.RecordError("other")
+		m.RecordRequest(label, "other", 0)
 		log.Error("failed to create request", "error", err)
 		return
 	}
+	req, span := telemetry.StartClient(req)
+	defer span.End()
+	record := func(result string, status int) {
+		m.RecordRequest(label, result, status)
+		objective.Record(result == "ok", time.Since(start))
+		telemetry.Annotate(req.Context(), status, attribute.String("tct.result", result))
+	}
+	if cfg.Profile != "" {
+		req.Header.Set(cfg.ProfileHeader, cfg.Profile)
+	}
+	if cfg.TransitTime {
+		transit.Set(req.Header, time.Now())
+	}
+	if cfg.RequestTimeout > 0 {
+		// Propagate the time budget to receivers that forward upstream
+		req.Header.Set(deadline.Header, strconv.FormatInt(cfg.RequestTimeout.Milliseconds(), 10))
+	}
 
 	resp, err := client.Do(req)
+	if err != nil && ctx.Err() != nil {
+		// Cancelled at shutdown, so the request has no outcome
+		log.Debug("request cancelled", "target", target)
+		return
+	}
 	duration := time.Since(start).Seconds()
-	m.ObserveResponseTime(duration)
+	m.ObserveResponseTime(req.Context(), label, duration)
 
 	if err != nil {
 		// Classify error
-		if ctx.Err() != nil {
-			m.RecordError("timeout")
-			log.Debug("request timeout", "target", target)
+		if errors.Is(err, errTooManyRedirects) {
+			record("redirect", 0)
+			log.Debug("redirect limit exceeded", "target", target)
 		} else {
-			m.RecordError("conn")
-			log.Debug("connection error", "target", target, "error", err)
+			cause := errorCause(err)
+			record(cause, 0)
+			log.Debug("request failed", "target", target, "cause", cause, "error", err)
 		}
 		return
 	}
 	defer resp.Body.Close()
 
 	// Drain response body
-	io.Copy(io.Discard, resp.Body)
+	n, _ := io.Copy(io.Discard, resp.Body)
+	m.ObserveResponseSize(label, n)
 
 	// Classify response
 	switch resp.StatusCode {
 	case http.StatusOK:
-		m.RecordSuccess()
+		record("ok", resp.StatusCode)
 		log.Debug("request successful", "target", target, "duration", duration)
 
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		// Only reached when redirects are not followed
+		record("redirect", resp.StatusCode)
+		log.Debug("redirect not followed", "target", target, "status", resp.StatusCode)
+
 	case http.StatusInternalServerError:
-		m.RecordError("http_500")
+		record("http_500", resp.StatusCode)
 		log.Debug("request failed", "target", target, "status", resp.StatusCode)
 
 	default:
-		m.RecordError("other")
+		record("other", resp.StatusCode)
 		log.Debug("unexpected status", "target", target, "status", resp.StatusCode)
 	}
 }
+
+// checkRedirect returns the client redirect policy for the configuration.
+// When redirects are not followed, the redirect response itself is returned.
+func checkRedirect(cfg *config.Config) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if !cfg.FollowRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) > cfg.MaxRedirects {
+			return errTooManyRedirects
+		}
+		return nil
+	}
+}
+
+// clientTLSConfig builds the client TLS configuration from the sender settings.
+func clientTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecure,
+	}
+
+	if cfg.TLSCAFile != "" {
+		pool, err := certs.LoadPool(cfg.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// Present a client certificate for mTLS receivers
+	if cfg.TLSClientCert != "" || cfg.TLSClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSClientCert, cfg.TLSClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key pair: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// errorCause classifies a request error without response, so dashboards
+// can distinguish a service that is down (dns_error, connect_refused), slow
+// (connect_timeout, read_timeout), or broken (tls_error, eof). Other
+// connection errors are classified as "conn".
+func errorCause(err error) string {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case isTLSError(err):
+		return "tls_error"
+	case errors.As(err, &dnsErr):
+		return "dns_error"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connect_refused"
+	case errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout():
+		return "connect_timeout"
+	case isTimeout(err):
+		return "read_timeout"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "eof"
+	default:
+		return "conn"
+	}
+}
+
+// isTimeout reports whether err is a timeout (e.g. the request timeout
+// expiring while awaiting the response).
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isTLSError reports whether err is caused by certificate verification or
+// the TLS handshake.
+func isTLSError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var authErr x509.UnknownAuthorityError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &hostErr) ||
+		errors.As(err, &invalidErr) ||
+		errors.As(err, &authErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		// net/http replaces the record header error of a plain HTTP server
+		strings.Contains(err.Error(), "server gave HTTP response to HTTPS client")
+}
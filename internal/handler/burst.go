@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"sync"
+
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/metrics"
+	"github.com/neox5/tct/internal/random"
+)
+
+// burstModel is a two-state Markov (Gilbert-Elliott) error model.
+// In the good state the profile error rate applies; in the bad state the
+// burst error rate applies. The state transitions once per request, so
+// errors cluster in time instead of being independent.
+type burstModel struct {
+	enter   float64 // P(good -> bad) per request
+	exit    float64 // P(bad -> good) per request
+	badRate float64 // error probability in the bad state
+	rng     *random.Rand
+	log     *logger.Logger
+	m       *metrics.ReceiverMetrics
+	mutex   sync.Mutex
+	bad     bool
+}
+
+// errorRate advances the model by one request and returns the error
+// probability for that request.
+func (b *burstModel) errorRate(goodRate float64) float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch {
+	case !b.bad && b.rng.Float64() < b.enter:
+		b.bad = true
+		b.log.Info("error burst started")
+		b.m.SetBurstState(true)
+	case b.bad && b.rng.Float64() < b.exit:
+		b.bad = false
+		b.log.Info("error burst ended")
+		b.m.SetBurstState(false)
+	}
+
+	if b.bad {
+		return b.badRate
+	}
+	return goodRate
+}
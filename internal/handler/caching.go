@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+)
+
+// caching emits cache headers on successful responses and answers
+// conditional GET and HEAD requests with 304 Not Modified.
+type caching struct {
+	control  string    // Cache-Control value, "" to omit
+	etag     string    // strong entity tag, "" to omit
+	modified time.Time // Last-Modified, zero to omit
+}
+
+// newCaching creates cache header emission for the static response body.
+// The entity tag is derived from the body and Last-Modified is the startup
+// time, since the body never changes. Returns nil if nothing is configured.
+func newCaching(cfg *config.Config, body []byte) *caching {
+	if cfg.CacheControl == "" && !cfg.CacheETag && !cfg.CacheLastModified {
+		return nil
+	}
+
+	c := &caching{control: cfg.CacheControl}
+	if cfg.CacheETag {
+		sum := sha256.Sum256(body)
+		c.etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+	}
+	if cfg.CacheLastModified {
+		c.modified = time.Now().UTC().Truncate(time.Second)
+	}
+	return c
+}
+
+// setHeaders writes the configured cache headers.
+func (c *caching) setHeaders(w http.ResponseWriter) {
+	if c.control != "" {
+		w.Header().Set("Cache-Control", c.control)
+	}
+	if c.etag != "" {
+		w.Header().Set("ETag", c.etag)
+	}
+	if !c.modified.IsZero() {
+		w.Header().Set("Last-Modified", c.modified.Format(http.TimeFormat))
+	}
+}
+
+// notModified reports whether a conditional GET or HEAD request matches the
+// current representation. If-None-Match takes precedence over
+// If-Modified-Since (RFC 9110, section 13.2.2).
+func (c *caching) notModified(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if c.etag == "" {
+			return false
+		}
+		for tag := range strings.SplitSeq(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			// Weak comparison: ignore the W/ prefix
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == c.etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !c.modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !c.modified.After(t)
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/neox5/tct/internal/errbody"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// responder writes response bodies, compressing them according to mode:
+// "off" never compresses, "auto" honors Accept-Encoding, "gzip" and
+// "deflate" always use that encoding.
+type responder struct {
+	mode string
+	m    *metrics.ReceiverMetrics
+}
+
+// write sends the status and body, applying the negotiated encoding.
+func (rs *responder) write(w http.ResponseWriter, r *http.Request, status int, body []byte) {
+	encoding := rs.encoding(r)
+	if encoding != "" {
+		if compressed, err := compress(encoding, body); err == nil {
+			body = compressed
+			w.Header().Set("Content-Encoding", encoding)
+		} else {
+			encoding = ""
+		}
+	}
+	if rs.mode == "auto" {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	rs.m.AddResponseBytes(encoding, len(body))
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// writeError renders an error body with errs and sends it with status.
+// Returns the rendered body.
+func (rs *responder) writeError(w http.ResponseWriter, r *http.Request, errs *errbody.Renderer, status int, message string) []byte {
+	contentType, body := errs.Render(r, status, message)
+	w.Header().Set("Content-Type", contentType)
+	rs.write(w, r, status, body)
+	return body
+}
+
+// encoding selects the content encoding for the request, or "" for none.
+func (rs *responder) encoding(r *http.Request) string {
+	switch rs.mode {
+	case "gzip", "deflate":
+		return rs.mode
+	case "auto":
+		accept := r.Header.Get("Accept-Encoding")
+		for _, enc := range []string{"gzip", "deflate"} {
+			if acceptsEncoding(accept, enc) {
+				return enc
+			}
+		}
+	}
+	return ""
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header lists enc
+// without a zero quality value.
+func acceptsEncoding(header, enc string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), enc) {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// compress encodes body with the given HTTP content encoding.
+// HTTP "deflate" is the zlib format (RFC 9110).
+func compress(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var zw io.WriteCloser
+	if encoding == "gzip" {
+		zw = gzip.NewWriter(&buf)
+	} else {
+		zw = zlib.NewWriter(&buf)
+	}
+
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ConfigHandler creates a handler for GET /config that returns the resolved
+// configuration as JSON, keyed by environment variable. settings is called
+// on every request so reloaded settings are reported, and must redact
+// secrets (see app.Settings).
+func ConfigHandler(settings func() map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings())
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// outageRequest is the request body for POST /control/outage.
+type outageRequest struct {
+	Duration string `json:"duration"`
+}
+
+// OutageControlHandler creates a handler for POST /control/outage that
+// starts an outage on demand. The body is JSON, e.g. {"duration": "30s"}.
+func OutageControlHandler(o *Outage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req outageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			http.Error(w, fmt.Sprintf("invalid duration %q (must be a positive duration, e.g. '30s')", req.Duration), http.StatusBadRequest)
+			return
+		}
+
+		until := o.Trigger(d)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{
+			"duration": d.String(),
+			"until":    until.Format(time.RFC3339),
+		})
+	}
+}
+
+// MetricsResetHandler creates a handler for POST /control/metrics/reset
+// that calls reset to zero the metrics, so sequential experiments against a
+// long-lived instance start from a clean slate.
+func MetricsResetHandler(reset func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reset()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// FlushHangsHandler creates a handler for POST /control/hangs/flush that
+// releases all hung requests.
+func FlushHangsHandler(h *Hangs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := h.Flush()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"released": n,
+		})
+	}
+}
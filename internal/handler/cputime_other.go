@@ -0,0 +1,11 @@
+//go:build !unix
+
+package handler
+
+import "time"
+
+// cpuTime is not supported on this platform; CPU-based shedding never
+// triggers.
+func cpuTime() time.Duration {
+	return 0
+}
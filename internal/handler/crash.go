@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/events"
+	"github.com/neox5/tct/internal/logger"
+)
+
+// crasher terminates the process after a number of requests or a duration,
+// simulating a crashing backend.
+type crasher struct {
+	cfg   *config.Config
+	log   *logger.Logger
+	ev    *events.Log
+	count atomic.Int64
+}
+
+// newCrasher creates a crasher and arms the duration trigger if configured.
+// Crashes are logged to ev. Returns nil if no crash trigger is configured.
+func newCrasher(cfg *config.Config, log *logger.Logger, ev *events.Log) *crasher {
+	if cfg.CrashAfterRequests == 0 && cfg.CrashAfter == 0 {
+		return nil
+	}
+
+	c := &crasher{cfg: cfg, log: log, ev: ev}
+	if cfg.CrashAfter > 0 {
+		time.AfterFunc(cfg.CrashAfter, func() {
+			c.crash(fmt.Sprintf("running for %v", cfg.CrashAfter))
+		})
+	}
+	return c
+}
+
+// observe counts a request and crashes once the request limit is reached.
+func (c *crasher) observe() {
+	if c.cfg.CrashAfterRequests == 0 {
+		return
+	}
+	if c.count.Add(1) >= int64(c.cfg.CrashAfterRequests) {
+		c.crash(fmt.Sprintf("%d requests", c.cfg.CrashAfterRequests))
+	}
+}
+
+// crash exits with the configured code or panics outside of any handler so
+// that net/http cannot recover it. Never returns.
+func (c *crasher) crash(reason string) {
+	c.log.Error("simulated crash", "after", reason, "panic", c.cfg.CrashPanic, "exit_code", c.cfg.CrashExitCode)
+	c.ev.Record("crash", reason, "trigger")
+	c.ev.Flush(2 * time.Second)
+
+	if c.cfg.CrashPanic {
+		go panic("tct: simulated crash after " + reason)
+		select {}
+	}
+	os.Exit(c.cfg.CrashExitCode)
+}
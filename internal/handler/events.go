@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/neox5/tct/internal/events"
+)
+
+// EventsHandler creates a handler for GET /events that returns the logged
+// experiment events, oldest first. Supported query parameters:
+//   - since: RFC 3339 timestamp or duration relative to now (e.g. "5m")
+func EventsHandler(ev *events.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var since time.Time
+		if v := r.URL.Query().Get("since"); v != "" {
+			var err error
+			if since, err = parseSince(v); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		list := ev.Query(since)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"count":  len(list),
+			"events": list,
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// Hangs tracks requests held without a response (hangs and outages) so they
+// can be released on client disconnect, server shutdown, or on demand.
+type Hangs struct {
+	log     *logger.Logger
+	m       *metrics.ReceiverMetrics
+	mutex   sync.Mutex
+	release chan struct{}
+	count   int
+}
+
+// NewHangs creates a hang registry. All held requests are released when ctx
+// is cancelled so they do not block server shutdown.
+func NewHangs(ctx context.Context, log *logger.Logger, m *metrics.ReceiverMetrics) *Hangs {
+	h := &Hangs{log: log, m: m, release: make(chan struct{})}
+	context.AfterFunc(ctx, func() { h.Flush() })
+	return h
+}
+
+// hold blocks until the client disconnects or the request is released,
+// then aborts the connection without a response. Never returns normally.
+func (h *Hangs) hold(r *http.Request) {
+	h.mutex.Lock()
+	release := h.release
+	h.count++
+	h.m.SetHungRequests(h.count)
+	h.mutex.Unlock()
+
+	// Consume the body so the server watches the connection and cancels
+	// the request context when the client disconnects
+	go io.Copy(io.Discard, r.Body)
+
+	select {
+	case <-r.Context().Done():
+	case <-release:
+	}
+
+	h.mutex.Lock()
+	h.count--
+	h.m.SetHungRequests(h.count)
+	h.mutex.Unlock()
+
+	panic(http.ErrAbortHandler)
+}
+
+// Flush releases all currently held requests and returns their number.
+func (h *Hangs) Flush() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	close(h.release)
+	h.release = make(chan struct{})
+	h.log.Info("hung requests released", "count", h.count)
+	return h.count
+}
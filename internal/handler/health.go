@@ -3,6 +3,8 @@ package handler
 
 import (
 	"net/http"
+
+	"github.com/neox5/tct/internal/server"
 )
 
 // Healthz handles GET /healthz requests.
@@ -19,3 +21,17 @@ func Readyz(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ready"))
 }
+
+// DrainingReadyz creates a handler for GET /readyz that returns 503 once
+// drain has begun, so the instance is taken out of rotation during
+// graceful termination.
+func DrainingReadyz(drain *server.Drain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if drain.Draining() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("draining"))
+			return
+		}
+		Readyz(w, r)
+	}
+}
@@ -2,121 +2,305 @@
 package handler
 
 import (
-	"math/rand"
+	"io"
 	"net/http"
-	"sync"
 	"time"
 
+	"github.com/neox5/tct/internal/behavior"
+	"github.com/neox5/tct/internal/bodysize"
 	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/deadline"
+	"github.com/neox5/tct/internal/dedup"
+	"github.com/neox5/tct/internal/errbody"
+	"github.com/neox5/tct/internal/events"
 	"github.com/neox5/tct/internal/logger"
 	"github.com/neox5/tct/internal/metrics"
+	"github.com/neox5/tct/internal/payload"
+	"github.com/neox5/tct/internal/random"
+	"github.com/neox5/tct/internal/transit"
 )
 
 // InboxHandler creates a handler for POST /inbox with behavior injection.
-func InboxHandler(cfg *config.Config, log *logger.Logger, m *metrics.ReceiverMetrics) http.HandlerFunc {
-	// Initialize outage state
-	outage := &outageState{
-		cfg:   cfg,
-		log:   log,
-		mutex: &sync.RWMutex{},
+// The behavior profile for each request is obtained from res and the size
+// rules in effect from sizes. Simulated crashes are logged to ev (may be
+// nil).
+func InboxHandler(cfg *config.Config, log *logger.Logger, m *metrics.ReceiverMetrics, rec *Recorder, res *behavior.Resolver, outage *Outage, hangs *Hangs, errs *errbody.Renderer, sizes func() bodysize.Rules, ev *events.Log) http.HandlerFunc {
+	// Seeded random source for all inbox decisions
+	rng := random.New(cfg.Seed, "inbox")
+
+	// Initialize burst error model if configured
+	var burst *burstModel
+	if cfg.ErrorModel == "burst" {
+		burst = &burstModel{
+			enter:   cfg.BurstEnterRate,
+			exit:    cfg.BurstExitRate,
+			badRate: cfg.BurstErrorRate,
+			rng:     random.New(cfg.Seed, "burst"),
+			log:     log,
+			m:       m,
+		}
 	}
 
-	// Start outage management if configured
-	if cfg.OutageAfter > 0 && cfg.OutageFor > 0 {
-		go outage.manage()
+	// Initialize upstream forwarding if configured
+	var up *upstream
+	if cfg.UpstreamURL != nil {
+		up = &upstream{
+			url:     cfg.UpstreamURL.String(),
+			timeout: cfg.UpstreamTimeout,
+			client:  &http.Client{},
+			m:       m,
+		}
+	}
+
+	// Prepare response body and encoding
+	rs := &responder{mode: cfg.Compression, m: m}
+	body := []byte("ok")
+	if cfg.ResponseSize > 0 {
+		body = payload.Generate(int(cfg.ResponseSize), cfg.ResponseCompressibility, random.New(cfg.Seed, "payload"))
+	}
+
+	// Emit cache headers and answer conditional requests if configured
+	caching := newCaching(cfg, body)
+
+	// Arm crash simulation if configured
+	crash := newCrasher(cfg, log, ev)
+
+	// Initialize request validation if configured
+	valid := newValidator(cfg)
+
+	// Start load shedding if configured
+	shed := newShedder(cfg, log, m)
+
+	// Initialize duplicate detection if configured
+	var cache *dedup.Cache
+	if cfg.DedupSize > 0 {
+		cache = dedup.New(cfg.DedupSize)
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+		x := newExchange(w, r)
+		p := res.Resolve(r)
+
+		// Record the transit delay from the sender
+		if cfg.TransitTime {
+			if d, ok := transit.Since(r); ok {
+				m.ObserveTransit(r.Context(), d)
+			}
+		}
+
+		// 0. Count request towards simulated crash
+		if crash != nil {
+			crash.observe()
+		}
 
 		// 1. Check if outage is active
-		if outage.isActive() {
-			m.RecordRequest("outage")
-			m.SetOutageState(true)
-			// Block indefinitely during outage (no response)
-			select {}
+		if outage.Active() || p.Outage {
+			rec.finish(x, "outage", 0)
+			// Hold without response until released
+			hangs.hold(r)
 		}
-		m.SetOutageState(false)
 
-		// 2. Apply hang decision
-		if rand.Float64() < cfg.HangRate {
-			m.RecordRequest("hang")
+		// 2. Shed load while over resource thresholds
+		if reason := shed.overloaded(); reason != "" {
+			rec.finish(x, "shed", http.StatusServiceUnavailable)
+			m.RecordShed(reason)
+			log.Debug("shedding request", "path", r.URL.Path, "reason", reason)
+			w.Header().Set("Retry-After", "1")
+			rs.writeError(w, r, errs, http.StatusServiceUnavailable, "overloaded")
+			return
+		}
+
+		// 3. Apply hang decision
+		if rng.Float64() < p.HangRate {
+			rec.finish(x, "hang", 0)
 			log.Debug("request hanging", "path", r.URL.Path)
-			// Block indefinitely (no response)
-			select {}
+			// Hold without response until released
+			hangs.hold(r)
 		}
 
-		// 3. Apply response delay + jitter
-		delay := cfg.ResponseDelay
-		if cfg.ResponseJitter > 0 {
-			jitter := time.Duration(rand.Int63n(int64(cfg.ResponseJitter)))
-			delay += jitter
+		// 4. Read request body (slowly or not at all if slow-read applies)
+		if rng.Float64() < p.SlowReadRate {
+			log.Debug("reading slowly", "path", r.URL.Path, "bytes_per_sec", cfg.SlowReadBPS)
+			n, complete := readSlowly(r, int(cfg.SlowReadBPS))
+			x.size = n
+			if !complete {
+				rec.finish(x, "slow_read", 0)
+				log.Debug("client gave up during slow read", "path", r.URL.Path, "bytes", n)
+				return
+			}
+			rec.finish(x, "slow_read", http.StatusOK)
+			rs.write(w, r, http.StatusOK, body)
+			return
 		}
-		if delay > 0 {
-			time.Sleep(delay)
+		var reqBody []byte
+		if valid.needsBody() {
+			reqBody, _ = io.ReadAll(r.Body)
+			x.size = int64(len(reqBody))
+		} else {
+			x.size, _ = io.Copy(io.Discard, r.Body)
 		}
 
-		// 4. Return error or success
-		if rand.Float64() < cfg.ErrorRate {
-			m.RecordRequest("error")
-			m.ObserveHandlerTime(time.Since(start).Seconds())
-			log.Debug("returning error", "path", r.URL.Path)
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("error"))
+		// Validate request and inject spurious client errors
+		if err := valid.check(r, reqBody); err != nil {
+			rec.finish(x, "invalid", http.StatusBadRequest)
+			log.Debug("rejecting invalid request", "path", r.URL.Path, "error", err)
+			rs.writeError(w, r, errs, http.StatusBadRequest, err.Error())
+			return
+		}
+		if rng.Float64() < p.BadRequestRate {
+			rec.finish(x, "bad_request", http.StatusBadRequest)
+			log.Debug("returning bad request", "path", r.URL.Path)
+			rs.writeError(w, r, errs, http.StatusBadRequest, "bad request")
 			return
 		}
 
-		m.RecordRequest("ok")
-		m.ObserveHandlerTime(time.Since(start).Seconds())
-		log.Debug("request successful", "path", r.URL.Path)
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("ok"))
-	}
-}
-
-// outageState manages the outage lifecycle.
-type outageState struct {
-	cfg    *config.Config
-	log    *logger.Logger
-	active bool
-	mutex  *sync.RWMutex
-}
+		// Apply the first size rule matching the body size
+		var extraDelay time.Duration
+		if rule := sizes().Match(x.size); rule != nil {
+			m.RecordSizeRule(rule.Name)
+			if rule.Reject {
+				rec.finish(x, "too_large", http.StatusRequestEntityTooLarge)
+				log.Debug("rejecting request by size", "path", r.URL.Path, "bytes", x.size, "rule", rule.Name)
+				rs.writeError(w, r, errs, http.StatusRequestEntityTooLarge, "payload too large")
+				return
+			}
+			p = rule.Apply(p)
+			extraDelay = rule.ExtraDelay
+		}
 
-// isActive returns whether an outage is currently active.
-func (o *outageState) isActive() bool {
-	o.mutex.RLock()
-	defer o.mutex.RUnlock()
-	return o.active
-}
+		// 5. Check for duplicate delivery (a new key stays in flight until its
+		// response is remembered and is released if the request is aborted)
+		key := r.Header.Get(cfg.DedupHeader)
+		if cache != nil && key != "" {
+			if seen, orig := cache.Seen(key); seen {
+				m.RecordDuplicate()
+				log.Debug("duplicate delivery", "path", r.URL.Path, "key", key, "mode", cfg.DedupMode)
+				switch {
+				case cfg.DedupMode == "replay" && orig != nil:
+					rec.finish(x, "duplicate", orig.Status)
+					for name, values := range orig.Header {
+						w.Header()[name] = values
+					}
+					rs.write(w, r, orig.Status, orig.Body)
+					return
+				case cfg.DedupMode != "count":
+					// Reject, or replay while the original is still in flight
+					rec.finish(x, "duplicate", http.StatusConflict)
+					rs.writeError(w, r, errs, http.StatusConflict, "duplicate")
+					return
+				}
+			} else {
+				defer cache.Release(key)
+			}
+		}
 
-// setActive sets the outage state.
-func (o *outageState) setActive(active bool) {
-	o.mutex.Lock()
-	defer o.mutex.Unlock()
-	o.active = active
-}
+		// 6. Apply response delay + jitter
+		x.delay = p.ResponseDelay + extraDelay
+		if p.ResponseJitter > 0 {
+			jitter := time.Duration(rng.Int63n(int64(p.ResponseJitter)))
+			x.delay += jitter
+		}
+		if x.delay > 0 {
+			time.Sleep(x.delay)
+		}
 
-// manage runs the outage lifecycle loop.
-func (o *outageState) manage() {
-	// Wait for initial delay
-	time.Sleep(o.cfg.OutageAfter)
+		// 7. Apply connection faults
+		if rng.Float64() < p.ResetRate {
+			rec.finish(x, "reset", 0)
+			log.Debug("resetting stream", "path", r.URL.Path, "proto", r.Proto)
+			// Aborts the response: RST_STREAM on HTTP/2, connection close on HTTP/1
+			panic(http.ErrAbortHandler)
+		}
+		if rng.Float64() < p.PanicRate {
+			status := 0
+			if cfg.PanicRecover {
+				status = http.StatusInternalServerError
+			}
+			rec.finish(x, "panic", status)
+			panic("tct: injected handler panic")
+		}
+		if r.ProtoMajor == 2 && rng.Float64() < p.GoawayRate {
+			m.RecordGoaway()
+			log.Debug("sending goaway", "path", r.URL.Path)
+			// The HTTP/2 server translates Connection: close into GOAWAY
+			w.Header().Set("Connection", "close")
+		}
+		if r.ProtoMajor == 1 && rng.Float64() < p.ConnCloseRate {
+			m.RecordConnClose()
+			log.Debug("closing connection after response", "path", r.URL.Path)
+			w.Header().Set("Connection", "close")
+		}
 
-	for {
-		// Start outage
-		o.log.Info("outage started", "duration", o.cfg.OutageFor)
-		o.setActive(true)
-		time.Sleep(o.cfg.OutageFor)
+		// 8. Call upstream dependency within the propagated deadline
+		if up != nil {
+			ctx, cancel := deadline.FromRequest(r)
+			err := up.call(ctx)
+			cancel()
+			if err != nil {
+				rec.finish(x, "upstream_error", http.StatusBadGateway)
+				log.Debug("upstream failed", "path", r.URL.Path, "upstream", cfg.UpstreamURL.Redacted(), "error", err)
+				errBody := rs.writeError(w, r, errs, http.StatusBadGateway, "upstream error")
+				remember(cache, key, w, http.StatusBadGateway, errBody)
+				return
+			}
+		}
 
-		// End outage
-		o.log.Info("outage ended")
-		o.setActive(false)
+		// 9. Apply redirect decision (chain continues at /redirect/{hop})
+		if rng.Float64() < p.RedirectRate {
+			rec.finish(x, "redirect", cfg.RedirectCode)
+			log.Debug("redirecting", "path", r.URL.Path, "depth", cfg.RedirectDepth, "code", cfg.RedirectCode)
+			http.Redirect(w, r, redirectPath(1), cfg.RedirectCode)
+			remember(cache, key, w, cfg.RedirectCode, nil)
+			return
+		}
 
-		// If not repeating, stop
-		if !o.cfg.OutageRepeat {
+		// 10. Return error or success
+		errorRate := p.ErrorRate
+		if burst != nil {
+			errorRate = burst.errorRate(p.ErrorRate)
+		}
+		if rng.Float64() < errorRate {
+			rec.finish(x, "error", http.StatusInternalServerError)
+			log.Debug("returning error", "path", r.URL.Path)
+			errBody := rs.writeError(w, r, errs, http.StatusInternalServerError, "error")
+			remember(cache, key, w, http.StatusInternalServerError, errBody)
 			return
 		}
 
-		// Wait for next cycle
-		time.Sleep(o.cfg.OutageAfter)
+		if caching != nil {
+			caching.setHeaders(w)
+			if caching.notModified(r) {
+				rec.finish(x, "not_modified", http.StatusNotModified)
+				log.Debug("not modified", "path", r.URL.Path)
+				w.WriteHeader(http.StatusNotModified)
+				remember(cache, key, w, http.StatusNotModified, nil)
+				return
+			}
+		}
+
+		rec.finish(x, "ok", http.StatusOK)
+		log.Debug("request successful", "path", r.URL.Path)
+		rs.write(w, r, http.StatusOK, body)
+		remember(cache, key, w, http.StatusOK, body)
+	}
+}
+
+// replayHeaders are the response headers recorded for replaying duplicates.
+// Encoding headers are left out, as replays are encoded per request.
+var replayHeaders = []string{"Content-Type", "Location", "Cache-Control", "ETag", "Last-Modified"}
+
+// remember records the response written to w for an idempotency key so
+// later duplicates can be replayed. No-op if duplicate detection is disabled
+// or key is empty.
+func remember(cache *dedup.Cache, key string, w http.ResponseWriter, status int, body []byte) {
+	if cache == nil || key == "" {
+		return
+	}
+	header := http.Header{}
+	for _, name := range replayHeaders {
+		if values := w.Header().Values(name); len(values) > 0 {
+			header[name] = values
+		}
 	}
+	cache.Store(key, status, header, body)
 }
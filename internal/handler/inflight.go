@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// Inflight returns middleware counting handler executions by the in-flight
+// gauge while they run, making server-side saturation observable.
+func Inflight(m *metrics.ReceiverMetrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.InflightInc()
+			defer m.InflightDec()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
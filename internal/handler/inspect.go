@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/neox5/tct/internal/inspect"
+)
+
+// InspectHandler creates a handler for GET /inspect/requests.
+// Supported query parameters:
+//   - outcome: exact outcome match (e.g. "ok", "error")
+//   - method: HTTP method (case-insensitive)
+//   - path: path prefix
+//   - since: RFC 3339 timestamp or duration relative to now (e.g. "5m")
+//   - limit: maximum number of entries returned
+func InspectHandler(buf *inspect.Buffer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entries := buf.Query(filter)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"count":    len(entries),
+			"requests": entries,
+		})
+	}
+}
+
+// parseFilter builds an inspection filter from query parameters.
+func parseFilter(r *http.Request) (inspect.Filter, error) {
+	q := r.URL.Query()
+	f := inspect.Filter{
+		Outcome: q.Get("outcome"),
+		Method:  q.Get("method"),
+		Path:    q.Get("path"),
+	}
+
+	if v := q.Get("since"); v != "" {
+		since, err := parseSince(v)
+		if err != nil {
+			return f, err
+		}
+		f.Since = since
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return f, fmt.Errorf("invalid limit %q", v)
+		}
+		f.Limit = limit
+	}
+
+	return f, nil
+}
+
+// parseSince parses a since parameter: an RFC 3339 timestamp or a duration
+// relative to now.
+func parseSince(v string) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q (must be RFC 3339 or duration)", v)
+}
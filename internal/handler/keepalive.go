@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// ManageKeepAlives runs the keep-alive chaos lifecycle loop, disabling
+// keep-alives server-wide via setEnabled during configured windows.
+// Returns immediately if no window is configured.
+func ManageKeepAlives(cfg *config.Config, log *logger.Logger, m *metrics.ReceiverMetrics, setEnabled func(bool)) {
+	if cfg.KeepAliveOffAfter == 0 || cfg.KeepAliveOffFor == 0 {
+		return
+	}
+
+	// Wait for initial delay
+	time.Sleep(cfg.KeepAliveOffAfter)
+
+	for {
+		// Disable keep-alives
+		log.Info("keep-alives disabled", "duration", cfg.KeepAliveOffFor)
+		setEnabled(false)
+		m.SetKeepAliveState(false)
+		time.Sleep(cfg.KeepAliveOffFor)
+
+		// Re-enable keep-alives
+		log.Info("keep-alives enabled")
+		setEnabled(true)
+		m.SetKeepAliveState(true)
+
+		// If not repeating, stop
+		if !cfg.KeepAliveOffRepeat {
+			return
+		}
+
+		// Wait for next cycle
+		time.Sleep(cfg.KeepAliveOffAfter)
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/logger"
+)
+
+// Outage manages the outage lifecycle. An outage is active while the
+// configured timer window is open or until a triggered outage expires.
+type Outage struct {
+	cfg      *config.Config
+	log      *logger.Logger
+	onChange func(active bool)
+	active   bool
+	until    time.Time
+	last     bool // last state reported to onChange
+	mutex    sync.RWMutex
+}
+
+// NewOutage creates the outage state and starts the timer lifecycle
+// if configured. If onChange is non-nil it is called on every transition
+// between active and inactive.
+func NewOutage(cfg *config.Config, log *logger.Logger, onChange func(active bool)) *Outage {
+	o := &Outage{
+		cfg:      cfg,
+		log:      log,
+		onChange: onChange,
+	}
+
+	// Start outage management if configured
+	if cfg.Outage.After > 0 && cfg.Outage.For > 0 {
+		go o.manage()
+	}
+	if len(cfg.Outage.At) > 0 && cfg.Outage.For > 0 {
+		go o.manageWindows()
+	}
+
+	return o
+}
+
+// Active returns whether an outage is currently active.
+func (o *Outage) Active() bool {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.isActive()
+}
+
+// isActive returns the outage state. Caller must hold the mutex.
+func (o *Outage) isActive() bool {
+	return o.active || time.Now().Before(o.until)
+}
+
+// Trigger starts an outage immediately for the given duration and returns
+// its end time. A trigger never shortens an already triggered outage.
+func (o *Outage) Trigger(d time.Duration) time.Time {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if until := time.Now().Add(d); until.After(o.until) {
+		o.until = until
+		time.AfterFunc(d, o.notify)
+	}
+	o.log.Info("outage triggered", "duration", d, "until", o.until)
+	o.report()
+	return o.until
+}
+
+// setActive sets the timer outage state.
+func (o *Outage) setActive(active bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.active = active
+	o.report()
+}
+
+// notify reports a pending state transition, e.g. when a triggered
+// outage expires.
+func (o *Outage) notify() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.report()
+}
+
+// report calls onChange if the state changed since the last report.
+// Caller must hold the mutex.
+func (o *Outage) report() {
+	active := o.isActive()
+	if active == o.last {
+		return
+	}
+	o.last = active
+	if o.onChange != nil {
+		o.onChange(active)
+	}
+}
+
+// manage runs the outage lifecycle loop.
+func (o *Outage) manage() {
+	// Wait for initial delay
+	time.Sleep(o.cfg.Outage.After)
+
+	for {
+		// Start outage
+		o.log.Info("outage started", "duration", o.cfg.Outage.For)
+		o.setActive(true)
+		time.Sleep(o.cfg.Outage.For)
+
+		// End outage
+		o.log.Info("outage ended")
+		o.setActive(false)
+
+		// If not repeating, stop
+		if !o.cfg.Outage.Repeat {
+			return
+		}
+
+		// Wait for next cycle
+		time.Sleep(o.cfg.Outage.After)
+	}
+}
+
+// manageWindows starts an outage at each configured offset since startup.
+func (o *Outage) manageWindows() {
+	start := time.Now()
+	offsets := slices.Sorted(slices.Values(o.cfg.Outage.At))
+	for _, at := range offsets {
+		time.Sleep(time.Until(start.Add(at)))
+		o.log.Info("outage window started", "at", at, "duration", o.cfg.Outage.For)
+		o.Trigger(o.cfg.Outage.For)
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/neox5/tct/internal/accesslog"
+	"github.com/neox5/tct/internal/inspect"
+	"github.com/neox5/tct/internal/metrics"
+	"github.com/neox5/tct/internal/slo"
+	"github.com/neox5/tct/internal/telemetry"
+)
+
+// exchange tracks the state of a single request through the behavior pipeline.
+type exchange struct {
+	w     http.ResponseWriter
+	r     *http.Request
+	start time.Time
+	size  int64         // request body bytes read
+	delay time.Duration // injected response delay
+}
+
+// newExchange starts tracking a request.
+func newExchange(w http.ResponseWriter, r *http.Request) *exchange {
+	return &exchange{w: w, r: r, start: time.Now()}
+}
+
+// Recorder records request outcomes to metrics, the inspection buffer, the
+// access log, and the trace span of the request.
+type Recorder struct {
+	m      *metrics.ReceiverMetrics
+	buf    *inspect.Buffer // nil if inspection is disabled
+	access *accesslog.Log  // nil if access logging is disabled
+	slo    *slo.Tracker    // nil if no SLO is configured
+	timing bool            // emit Server-Timing headers
+}
+
+// NewRecorder creates a recorder shared by receiver handlers.
+// buf, access, and slo may be nil to disable inspection, access logging,
+// and SLO tracking. If timing is set, responses carry a Server-Timing
+// header.
+func NewRecorder(m *metrics.ReceiverMetrics, buf *inspect.Buffer, access *accesslog.Log, slo *slo.Tracker, timing bool) *Recorder {
+	return &Recorder{m: m, buf: buf, access: access, slo: slo, timing: timing}
+}
+
+// finish records the outcome of a request. A status of 0 marks requests that
+// never receive a response (hang, outage); their handler time and size are
+// not observed. Must be called before the response header is written.
+func (rec *Recorder) finish(x *exchange, outcome string, status int) {
+	elapsed := time.Since(x.start)
+	fault := faultOf(x, outcome)
+
+	rec.m.RecordRequest(outcome)
+	rec.m.ObserveInjectedDelay(outcome, x.delay)
+	rec.slo.Record(status != 0 && status < 500, elapsed)
+	if status != 0 {
+		rec.m.ObserveHandlerTime(x.r.Context(), fault, elapsed.Seconds())
+		rec.m.ObserveRequestSize(outcome, x.size)
+		if rec.timing {
+			x.w.Header().Set("Server-Timing", serverTiming(x.delay, elapsed))
+		}
+	}
+
+	telemetry.Annotate(x.r.Context(), status,
+		attribute.String("tct.outcome", outcome),
+		attribute.String("tct.fault", fault),
+		attribute.Float64("tct.delay_ms", milliseconds(x.delay)),
+		attribute.Bool("tct.outage", outcome == "outage"),
+	)
+
+	if rec.buf != nil {
+		rec.buf.Add(inspect.Entry{
+			Time:       x.start,
+			Method:     x.r.Method,
+			Path:       x.r.URL.Path,
+			Remote:     x.r.RemoteAddr,
+			Headers:    x.r.Header.Clone(),
+			Size:       x.size,
+			DurationMs: milliseconds(elapsed),
+			Outcome:    outcome,
+			Status:     status,
+		})
+	}
+
+	if rec.access != nil {
+		rec.access.Write(accesslog.Entry{
+			Time:      x.start,
+			Client:    x.r.RemoteAddr,
+			Method:    x.r.Method,
+			Path:      x.r.URL.Path,
+			Status:    status,
+			Size:      x.size,
+			LatencyMs: milliseconds(elapsed),
+			DelayMs:   milliseconds(x.delay),
+			Outcome:   outcome,
+			Fault:     fault,
+		})
+	}
+}
+
+// faultOf returns the injected fault responsible for the outcome, or an
+// empty string if the request was served normally.
+func faultOf(x *exchange, outcome string) string {
+	if outcome != "ok" {
+		return outcome
+	}
+	if x.delay > 0 {
+		return "delay"
+	}
+	return ""
+}
+
+// serverTiming returns the Server-Timing header value breaking down the
+// elapsed handler time into the injected delay and the remaining real
+// handler time, so client-side tools can show where the time went.
+func serverTiming(delay, elapsed time.Duration) string {
+	return fmt.Sprintf(`delay;dur=%.3f;desc="injected delay", handler;dur=%.3f;desc="handler time"`,
+		milliseconds(delay), milliseconds(max(elapsed-delay, 0)))
+}
+
+// milliseconds converts a duration to fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// Recover returns middleware counting and logging handler panics with their
+// stack trace. If respond is set, panics are turned into 500 responses;
+// otherwise the connection is torn down as net/http does. Deliberate aborts
+// (http.ErrAbortHandler) are passed through.
+func Recover(log *logger.Logger, m *metrics.ReceiverMetrics, respond bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				m.RecordPanic()
+				log.Warn("recovered handler panic", "path", r.URL.Path, "panic", err, "stack", string(debug.Stack()))
+				if !respond {
+					panic(http.ErrAbortHandler)
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("internal error"))
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/logger"
+)
+
+// redirectPath returns the location of the given hop in a redirect chain.
+func redirectPath(hop int) string {
+	return fmt.Sprintf("/redirect/%d", hop)
+}
+
+// RedirectHandler creates a handler for /redirect/{hop} that continues a
+// redirect chain started by the inbox handler. Each hop redirects to the next
+// until the configured depth is reached, then responds with 200 OK.
+// All methods are accepted since 301/302 redirects may switch POST to GET.
+func RedirectHandler(cfg *config.Config, log *logger.Logger, rec *Recorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		x := newExchange(w, r)
+
+		hop, err := strconv.Atoi(r.PathValue("hop"))
+		if err != nil || hop < 1 {
+			http.NotFound(w, r)
+			return
+		}
+		x.size, _ = io.Copy(io.Discard, r.Body)
+
+		// Continue chain until final hop
+		if hop < cfg.RedirectDepth {
+			rec.finish(x, "redirect", cfg.RedirectCode)
+			log.Debug("redirecting", "path", r.URL.Path, "hop", hop, "code", cfg.RedirectCode)
+			http.Redirect(w, r, redirectPath(hop+1), cfg.RedirectCode)
+			return
+		}
+
+		rec.finish(x, "ok", http.StatusOK)
+		log.Debug("redirect chain complete", "path", r.URL.Path, "hops", hop)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}
+}
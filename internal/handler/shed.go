@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/neox5/tct/internal/config"
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// shedder rejects requests while the process's own CPU usage or goroutine
+// count exceeds the configured thresholds, modeling a backend that sheds
+// load instead of degrading.
+type shedder struct {
+	cfg    *config.Config
+	log    *logger.Logger
+	m      *metrics.ReceiverMetrics
+	reason atomic.Pointer[string] // nil while not overloaded
+}
+
+// newShedder creates a shedder and starts resource sampling.
+// Returns nil if no threshold is configured.
+func newShedder(cfg *config.Config, log *logger.Logger, m *metrics.ReceiverMetrics) *shedder {
+	if cfg.ShedCPU == 0 && cfg.ShedGoroutines == 0 {
+		return nil
+	}
+
+	s := &shedder{cfg: cfg, log: log, m: m}
+	go s.sample()
+	return s
+}
+
+// overloaded returns the reason requests are currently being shed
+// ("cpu" or "goroutines"), or "" if within thresholds or s is nil.
+func (s *shedder) overloaded() string {
+	if s == nil {
+		return ""
+	}
+	if reason := s.reason.Load(); reason != nil {
+		return *reason
+	}
+	return ""
+}
+
+// sample periodically measures CPU usage and goroutine count and updates
+// the shedding state.
+func (s *shedder) sample() {
+	prevCPU, prevWall := cpuTime(), time.Now()
+
+	for range time.Tick(s.cfg.ShedInterval) {
+		// CPU usage as a fraction of total capacity across all cores
+		cpu, wall := cpuTime(), time.Now()
+		usage := (cpu - prevCPU).Seconds() / wall.Sub(prevWall).Seconds() / float64(runtime.NumCPU())
+		prevCPU, prevWall = cpu, wall
+		s.m.SetCPUUsage(usage)
+
+		var reason string
+		switch {
+		case s.cfg.ShedCPU > 0 && usage > s.cfg.ShedCPU:
+			reason = "cpu"
+		case s.cfg.ShedGoroutines > 0 && runtime.NumGoroutine() > s.cfg.ShedGoroutines:
+			reason = "goroutines"
+		}
+
+		prev := s.overloaded()
+		if reason == prev {
+			continue
+		}
+		if reason == "" {
+			s.reason.Store(nil)
+			s.log.Info("load shedding stopped")
+		} else {
+			s.reason.Store(&reason)
+			s.log.Warn("load shedding started", "reason", reason, "cpu", usage, "goroutines", runtime.NumGoroutine())
+		}
+	}
+}
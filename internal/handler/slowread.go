@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// slowReadTick is the target interval between reads of a slow-read body.
+const slowReadTick = 100 * time.Millisecond
+
+// readSlowly consumes the request body at roughly bps bytes per second.
+// A bps of 0 stops reading entirely until the client disconnects.
+// Returns the number of bytes read and whether the body was fully consumed.
+func readSlowly(r *http.Request, bps int) (int64, bool) {
+	ctx := r.Context()
+
+	// Stall: never read, wait for client to give up
+	if bps <= 0 {
+		<-ctx.Done()
+		return 0, false
+	}
+
+	// Read in small chunks so the rate stays smooth for low bps values
+	chunk := max(bps/int(time.Second/slowReadTick), 1)
+	interval := time.Duration(float64(time.Second) * float64(chunk) / float64(bps))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	buf := make([]byte, chunk)
+	var total int64
+	for {
+		select {
+		case <-ctx.Done():
+			return total, false
+		case <-ticker.C:
+			n, err := r.Body.Read(buf)
+			total += int64(n)
+			if err != nil {
+				return total, errors.Is(err, io.EOF)
+			}
+		}
+	}
+}
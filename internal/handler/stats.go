@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// StatsHandler returns a handler for GET /stats that reports the request
+// counts by result and latency percentiles since startup as JSON, for
+// scripts that do not parse the Prometheus exposition format.
+func StatsHandler(stats *metrics.Stats) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sum, err := stats.Total()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sum)
+	}
+}
+
+// HeatmapHandler returns a handler for GET /heatmap that reports the latency
+// histograms of the recent time slices as JSON for rendering heatmaps.
+func HeatmapHandler(heatmap *metrics.Heatmap) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(heatmap.Report())
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/neox5/tct/internal/deadline"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// upstream forwards requests to a downstream dependency before the
+// receiver responds, enabling multi-hop topologies.
+type upstream struct {
+	url     string
+	timeout time.Duration
+	client  *http.Client
+	m       *metrics.ReceiverMetrics
+}
+
+// call sends a POST to the upstream within the remaining budget of ctx and
+// the configured timeout, propagating the budget via the deadline header.
+// Returns an error for transport failures and 5xx responses.
+func (u *upstream) call(ctx context.Context) error {
+	if u.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url, nil)
+	if err != nil {
+		u.m.RecordUpstream(ctx, "error", 0)
+		return fmt.Errorf("failed to create upstream request: %w", err)
+	}
+	deadline.Set(ctx, req.Header)
+
+	start := time.Now()
+	resp, err := u.client.Do(req)
+	elapsed := time.Since(start).Seconds()
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			u.m.RecordUpstream(ctx, "timeout", elapsed)
+		} else {
+			u.m.RecordUpstream(ctx, "error", elapsed)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 500 {
+		u.m.RecordUpstream(ctx, "http_5xx", elapsed)
+		return fmt.Errorf("upstream returned %d", resp.StatusCode)
+	}
+
+	u.m.RecordUpstream(ctx, "ok", elapsed)
+	return nil
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/neox5/tct/internal/config"
+)
+
+// validator checks requests against the configured content type, required
+// headers, and JSON well-formedness.
+type validator struct {
+	contentType string
+	headers     []string
+	json        bool
+}
+
+// newValidator creates a validator from the configuration.
+// Returns nil if no validation is configured.
+func newValidator(cfg *config.Config) *validator {
+	v := &validator{contentType: cfg.ValidateContentType, headers: cfg.ValidateHeaders, json: cfg.ValidateJSON}
+	if v.contentType == "" && len(v.headers) == 0 && !v.json {
+		return nil
+	}
+	return v
+}
+
+// needsBody reports whether check inspects the request body.
+func (v *validator) needsBody() bool {
+	return v != nil && v.json
+}
+
+// check returns a description of the first validation failure, or nil.
+// No-op for a nil validator.
+func (v *validator) check(r *http.Request, body []byte) error {
+	if v == nil {
+		return nil
+	}
+	if v.contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !strings.EqualFold(mediaType, v.contentType) {
+			return fmt.Errorf("content type must be %s", v.contentType)
+		}
+	}
+	for _, h := range v.headers {
+		if r.Header.Get(h) == "" {
+			return fmt.Errorf("missing required header %s", h)
+		}
+	}
+	if v.json && !json.Valid(body) {
+		return fmt.Errorf("body is not valid JSON")
+	}
+	return nil
+}
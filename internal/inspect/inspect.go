@@ -0,0 +1,116 @@
+// Package inspect provides a bounded in-memory buffer of recently received
+// requests that can be queried for verification during experiments.
+package inspect
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sensitiveHeaders carry credentials; their values are not stored.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// redacted replaces the values of sensitive headers.
+const redacted = "[redacted]"
+
+// Entry describes a single received request. Values of credential headers
+// (e.g. Authorization, Cookie) are redacted when the entry is added.
+type Entry struct {
+	Time       time.Time   `json:"time"`
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Remote     string      `json:"remote"`
+	Headers    http.Header `json:"headers"`
+	Size       int64       `json:"size"`
+	DurationMs float64     `json:"duration_ms"`
+	Outcome    string      `json:"outcome"`
+	Status     int         `json:"status"`
+}
+
+// Filter selects entries from the buffer. Zero values match everything.
+type Filter struct {
+	Outcome string
+	Method  string
+	Path    string // prefix match
+	Since   time.Time
+	Limit   int
+}
+
+// Buffer is a fixed-size ring of the most recent entries.
+// It is safe for concurrent use.
+type Buffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// NewBuffer creates a buffer holding up to size entries.
+func NewBuffer(size int) *Buffer {
+	return &Buffer{
+		entries: make([]Entry, size),
+	}
+}
+
+// Add stores an entry, overwriting the oldest one when the buffer is full.
+// The headers of e are redacted in place.
+func (b *Buffer) Add(e Entry) {
+	for _, name := range sensitiveHeaders {
+		if values := e.Headers.Values(name); len(values) > 0 {
+			e.Headers.Set(name, redacted)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Query returns entries matching the filter, newest first.
+func (b *Buffer) Query(f Filter) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.entries)
+	}
+
+	result := []Entry{}
+	for i := 1; i <= count; i++ {
+		e := b.entries[(b.next-i+len(b.entries))%len(b.entries)]
+		if !f.matches(e) {
+			continue
+		}
+		result = append(result, e)
+		if f.Limit > 0 && len(result) >= f.Limit {
+			break
+		}
+	}
+
+	return result
+}
+
+// matches reports whether the entry satisfies the filter.
+func (f Filter) matches(e Entry) bool {
+	if f.Outcome != "" && e.Outcome != f.Outcome {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(e.Method, f.Method) {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(e.Path, f.Path) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
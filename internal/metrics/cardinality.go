@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// overflowValue replaces label values beyond the cardinality limit.
+const overflowValue = "other"
+
+// newFoldedCounter creates the counter of observations of a mode whose
+// label value was folded by the cardinality limit, labeled with the label.
+func newFoldedCounter(f promauto.Factory, mode string) *prometheus.CounterVec {
+	return f.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "tct_" + mode + "_label_values_folded_total",
+			Help: "Total number of observations with a label value folded into \"other\" by the cardinality limit, by label",
+		},
+		[]string{"label"},
+	)
+}
+
+// labelGuard limits the distinct values of one dynamic label. It is shared
+// by all metrics with the label, so their series stay consistent.
+type labelGuard struct {
+	mu     sync.Mutex
+	seen   map[string]struct{}
+	limit  int
+	folded prometheus.Counter
+}
+
+// newLabelGuard creates a guard for label allowing limit distinct values
+// (0 = unlimited) and counting folds in folded.
+func newLabelGuard(folded *prometheus.CounterVec, label string, limit int) *labelGuard {
+	return &labelGuard{
+		seen:   map[string]struct{}{},
+		limit:  limit,
+		folded: folded.WithLabelValues(label),
+	}
+}
+
+// value returns v if it is known or the limit is not reached yet, otherwise
+// "other".
+func (g *labelGuard) value(v string) string {
+	if g.limit == 0 {
+		return v
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.seen[v]; ok {
+		return v
+	}
+	if len(g.seen) < g.limit {
+		g.seen[v] = struct{}{}
+		return v
+	}
+	g.folded.Inc()
+	return overflowValue
+}
+
+// reset forgets the known values, e.g. after the series were removed.
+func (g *labelGuard) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	clear(g.seen)
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// connStates are the states of open connections.
+var connStates = []string{"new", "active", "idle"}
+
+// RegisterConnections registers gauges of the open connections of a mode,
+// in total and by state ("new" before the first request, "active", "idle"),
+// reported by states at scrape time. Together with a connection limit this
+// makes connection exhaustion observable.
+func RegisterConnections(reg prometheus.Registerer, mode string, states func() map[string]int) {
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "tct_" + mode + "_open_connections",
+		Help: "Number of open connections",
+	}, func() float64 {
+		var open int
+		for _, n := range states() {
+			open += n
+		}
+		return float64(open)
+	}))
+	for _, state := range connStates {
+		reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "tct_" + mode + "_connections",
+			Help:        "Number of open connections by state",
+			ConstLabels: prometheus.Labels{"state": state},
+		}, func() float64 {
+			return float64(states()[state])
+		}))
+	}
+}
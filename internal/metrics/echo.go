@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// EchoMetrics holds all Prometheus metrics for echo mode.
+type EchoMetrics struct {
+	EventsTotal *prometheus.CounterVec
+	BytesTotal  prometheus.Counter
+	Connections prometheus.Gauge
+}
+
+// NewEchoMetrics creates echo metrics and registers them with reg.
+func NewEchoMetrics(reg prometheus.Registerer) *EchoMetrics {
+	f := promauto.With(reg)
+	return &EchoMetrics{
+		EventsTotal: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_echo_events_total",
+				Help: "Total number of received reads (TCP) or datagrams (UDP) by outcome",
+			},
+			[]string{"outcome"},
+		),
+
+		BytesTotal: f.NewCounter(prometheus.CounterOpts{
+			Name: "tct_echo_bytes_total",
+			Help: "Total number of bytes echoed back to clients",
+		}),
+
+		Connections: f.NewGauge(prometheus.GaugeOpts{
+			Name: "tct_echo_connections",
+			Help: "Number of currently open TCP connections",
+		}),
+	}
+}
+
+// RecordEvent increments the event counter for the specified outcome.
+// Valid outcomes: "echoed", "dropped", "reset"
+func (m *EchoMetrics) RecordEvent(outcome string) {
+	m.EventsTotal.WithLabelValues(outcome).Inc()
+}
+
+// AddBytes adds n to the echoed bytes counter.
+func (m *EchoMetrics) AddBytes(n int) {
+	m.BytesTotal.Add(float64(n))
+}
+
+// ConnectionsInc increments the open connection gauge.
+func (m *EchoMetrics) ConnectionsInc() {
+	m.Connections.Inc()
+}
+
+// ConnectionsDec decrements the open connection gauge.
+func (m *EchoMetrics) ConnectionsDec() {
+	m.Connections.Dec()
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// Exposition is how metrics are exposed to external systems: with a name
+// prefix replacing tct and constant labels added to all metrics, so
+// simultaneous experiments in the same Prometheus can be told apart. A nil
+// Exposition exposes metrics unchanged.
+type Exposition struct {
+	namespace string
+	labels    []*dto.LabelPair // sorted by name
+}
+
+// NewExposition creates an exposition using ns as name prefix instead of tct
+// and adding the constant labels. Labels already present on a metric take
+// precedence.
+func NewExposition(ns string, labels map[string]string) *Exposition {
+	e := &Exposition{namespace: ns}
+	for name, value := range labels {
+		e.labels = append(e.labels, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+	}
+	slices.SortFunc(e.labels, func(a, b *dto.LabelPair) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+	return e
+}
+
+// name returns the exposed name of a metric.
+func (e *Exposition) name(name string) string {
+	if e == nil {
+		return name
+	}
+	if rest, ok := strings.CutPrefix(name, "tct_"); ok {
+		return e.namespace + "_" + rest
+	}
+	return name
+}
+
+// Exposed returns a gatherer of the metrics of g as exposed to external
+// systems. Internal consumers (e.g. Stats) use g directly.
+func (e *Exposition) Exposed(g prometheus.Gatherer) prometheus.Gatherer {
+	if e == nil || (e.namespace == "tct" && len(e.labels) == 0) {
+		return g
+	}
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, mf := range families {
+			mf.Name = proto.String(e.name(mf.GetName()))
+			for _, m := range mf.GetMetric() {
+				m.Label = e.withLabels(m.GetLabel())
+			}
+		}
+		return families, err
+	})
+}
+
+// withLabels adds the constant labels missing from labels, keeping the
+// labels sorted by name.
+func (e *Exposition) withLabels(labels []*dto.LabelPair) []*dto.LabelPair {
+	if e == nil {
+		return labels
+	}
+	for _, c := range e.labels {
+		if !slices.ContainsFunc(labels, func(l *dto.LabelPair) bool { return l.GetName() == c.GetName() }) {
+			labels = append(labels, c)
+		}
+	}
+	slices.SortFunc(labels, func(a, b *dto.LabelPair) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+	return labels
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Heatmap records the latency histogram of the requests summarized by a
+// Stats in fixed time slices, so the evolution of latency over a run (e.g.
+// across phases) can be rendered as a heatmap rather than a single
+// aggregate histogram.
+type Heatmap struct {
+	stats *Stats
+	slice time.Duration
+
+	mu     sync.Mutex
+	bounds []float64 // bucket upper bounds in seconds
+	slices []HeatmapSlice
+	size   int
+}
+
+// HeatmapSlice holds the latency distribution of one time slice. Counts has
+// one entry per bucket bound and a last entry for the overflow bucket.
+type HeatmapSlice struct {
+	Start  time.Time `json:"start"`
+	Count  float64   `json:"count"`
+	Counts []float64 `json:"counts"`
+}
+
+// HeatmapReport is the heatmap of the recorded slices, oldest first.
+type HeatmapReport struct {
+	SliceSeconds float64        `json:"slice_seconds"`
+	BoundsMs     []float64      `json:"bounds_ms"`
+	Slices       []HeatmapSlice `json:"slices"`
+}
+
+// NewHeatmap creates a heatmap of the latency histogram of stats keeping the
+// last size slices of the given width.
+func NewHeatmap(stats *Stats, slice time.Duration, size int) *Heatmap {
+	return &Heatmap{stats: stats, slice: slice, size: size}
+}
+
+// Run records a slice at the end of every slice width until the context is
+// cancelled.
+func (h *Heatmap) Run(ctx context.Context) {
+	prev, _ := h.stats.snapshot()
+	start := time.Now()
+	ticker := time.NewTicker(h.slice)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cur, err := h.stats.snapshot()
+			if err != nil {
+				continue
+			}
+			h.add(start, prev, cur)
+			prev, start = cur, now
+		}
+	}
+}
+
+// add records the observations between two snapshots as the slice starting
+// at start. If the metrics were reset in between, the slice covers the
+// observations since the reset.
+func (h *Heatmap) add(start time.Time, prev, cur statsSnapshot) {
+	if cur.count < prev.count {
+		prev = statsSnapshot{}
+	}
+	bounds := sortedBounds(cur.buckets)
+	s := HeatmapSlice{Start: start, Count: cur.count - prev.count, Counts: make([]float64, len(bounds)+1)}
+	var below float64
+	for i, le := range bounds {
+		n := cur.buckets[le] - prev.buckets[le]
+		s.Counts[i] = n - below
+		below = n
+	}
+	s.Counts[len(bounds)] = s.Count - below
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(bounds) > 0 {
+		h.bounds = bounds
+	}
+	h.slices = append(h.slices, s)
+	if len(h.slices) > h.size {
+		h.slices = h.slices[len(h.slices)-h.size:]
+	}
+}
+
+// Reset discards the recorded slices. Call it when resetting the metrics.
+// No-op on a nil heatmap.
+func (h *Heatmap) Reset() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.slices = nil
+}
+
+// Report returns the recorded slices, oldest first.
+func (h *Heatmap) Report() HeatmapReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := HeatmapReport{SliceSeconds: h.slice.Seconds(), BoundsMs: []float64{}, Slices: []HeatmapSlice{}}
+	for _, le := range h.bounds {
+		r.BoundsMs = append(r.BoundsMs, math.Round(le*1e6)/1e3)
+	}
+	for _, s := range h.slices {
+		if len(s.Counts) != len(h.bounds)+1 {
+			// Recorded without observations (no buckets known yet)
+			s.Counts = make([]float64, len(h.bounds)+1)
+		}
+		r.Slices = append(r.Slices, s)
+	}
+	return r
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/neox5/tct/internal/version"
+)
+
+// RegisterInfo registers info gauges (always 1) describing what produced
+// the metrics of reg: tct_build_info with the version, Go version, and mode,
+// and tct_config_info with the given settings as labels (keyed by
+// environment variable, named without the TCT_ prefix in lower case).
+func RegisterInfo(reg prometheus.Registerer, mode string, settings map[string]string) {
+	build := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "tct_build_info",
+		Help: "Build information (always 1)",
+		ConstLabels: prometheus.Labels{
+			"version":    version.String(),
+			"go_version": runtime.Version(),
+			"mode":       mode,
+		},
+	})
+	build.Set(1)
+
+	labels := prometheus.Labels{"mode": mode}
+	for key, value := range settings {
+		labels[strings.ToLower(strings.TrimPrefix(key, "TCT_"))] = value
+	}
+	config := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "tct_config_info",
+		Help:        "Explicitly configured settings as labels (always 1)",
+		ConstLabels: labels,
+	})
+	config.Set(1)
+
+	reg.MustRegister(build, config)
+}
@@ -2,13 +2,99 @@
 package metrics
 
 import (
+	"context"
 	"net/http"
+	"runtime"
+	"slices"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Options are the settings of a metric set.
+type Options struct {
+	// NativeHistograms makes latency histograms also collect native
+	// (sparse) histograms for high-resolution analysis. Classic buckets are
+	// kept, so scrapers without native histogram support are unaffected.
+	NativeHistograms bool
+
+	// LabelLimit caps the number of distinct values of dynamic labels (e.g.
+	// target, profile), so an experiment cannot create unbounded series in
+	// Prometheus. Values beyond the limit are folded into "other". A limit
+	// of 0 disables the cap.
+	LabelLimit int
+
+	// StatsD receives latency observations as timings (nil if disabled).
+	StatsD *StatsD
+
+	// LatencyBound is added to the default buckets of latency histograms
+	// (0 for none), so a latency limit (e.g. an asserted p99) can be
+	// resolved exactly even beyond the largest default bucket.
+	LatencyBound time.Duration
+}
+
+// histogram adds the latency bound and native histogram settings to opts
+// if configured.
+func (o Options) histogram(opts prometheus.HistogramOpts) prometheus.HistogramOpts {
+	if o.LatencyBound > 0 {
+		opts.Buckets = slices.Clone(prometheus.DefBuckets)
+		if bound := o.LatencyBound.Seconds(); !slices.Contains(opts.Buckets, bound) {
+			opts.Buckets = append(opts.Buckets, bound)
+			slices.Sort(opts.Buckets)
+		}
+	}
+	if o.NativeHistograms {
+		opts.NativeHistogramBucketFactor = 1.1
+		opts.NativeHistogramMaxBucketNumber = 160
+		opts.NativeHistogramMinResetDuration = time.Hour
+	}
+	return opts
+}
+
+// sizeBuckets are the buckets of body size histograms: 64B to 16MiB.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 10)
+
+// observe records v on o with the trace ID of the sampled span in ctx, if
+// any, as exemplar, so dashboards can link latency to an example trace.
+func observe(ctx context.Context, o prometheus.Observer, v float64) {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(v, prometheus.Labels{"trace_id": sc.TraceID().String()})
+			return
+		}
+	}
+	o.Observe(v)
+}
+
+// NewRegistry creates a registry for the metrics of one metric set,
+// optionally with the Go runtime (go_*) and process (process_*) collectors
+// the default registry has. Separate registries let several metric sets
+// coexist in one process. The goroutine count is always exported as
+// tct_goroutines, so the overhead of the tool stays observable.
+func NewRegistry(goRuntime, process bool) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	if goRuntime {
+		reg.MustRegister(collectors.NewGoCollector())
+	}
+	if process {
+		reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+	reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "tct_goroutines",
+		Help: "Number of goroutines of the process",
+	}, func() float64 { return float64(runtime.NumGoroutine()) }))
+	return reg
+}
+
 // Handler returns an HTTP handler for the /metrics endpoint.
-// This handler exposes all registered Prometheus metrics.
-func Handler() http.Handler {
-	return promhttp.Handler()
+// This handler exposes all metrics registered with reg as exposed by expo,
+// including exemplars when scraped in the OpenMetrics format.
+func Handler(reg *prometheus.Registry, expo *Exposition) http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		reg,
+		promhttp.HandlerFor(expo.Exposed(reg), promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
 }
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// newPhaseGauge creates the info gauge of the active experiment phases of a
+// mode, labeled with the source of the phase (scenario, schedule, outage,
+// rate_schedule) and its name. Only active phases have a series, so results
+// can be sliced by phase by joining on it.
+func newPhaseGauge(f promauto.Factory, mode string) *prometheus.GaugeVec {
+	return f.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tct_" + mode + "_phase",
+			Help: "Active experiment phases by source (1=active)",
+		},
+		[]string{"source", "phase"},
+	)
+}
+
+// phaseNotifier calls a hook on the phase updates of a metric set.
+type phaseNotifier struct {
+	hook atomic.Pointer[func(source, phase string, active bool)]
+}
+
+// NotifyPhases makes fn be called on every phase update of the metric set,
+// e.g. to log phase transitions. Updates may repeat the current state.
+func (n *phaseNotifier) NotifyPhases(fn func(source, phase string, active bool)) {
+	n.hook.Store(&fn)
+}
+
+// setPhase marks a phase of the source active in g or removes it.
+func (n *phaseNotifier) setPhase(g *prometheus.GaugeVec, source, phase string, active bool) {
+	if fn := n.hook.Load(); fn != nil {
+		(*fn)(source, phase, active)
+	}
+	if active {
+		g.WithLabelValues(source, phase).Set(1)
+	} else {
+		g.DeleteLabelValues(source, phase)
+	}
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/neox5/tct/internal/logger"
+)
+
+// Pusher pushes all metrics to a Prometheus Pushgateway, for short-lived
+// runs that finish before they could be scraped.
+type Pusher struct {
+	p *push.Pusher
+}
+
+// NewPusher creates a pusher of the metrics gathered by g for the
+// Pushgateway at u (credentials in u are used for basic auth). Metrics are
+// grouped by job and mode; each push replaces the metrics of the previous
+// one.
+func NewPusher(g prometheus.Gatherer, u *url.URL, job, mode string) *Pusher {
+	p := push.New(u.String(), job).
+		Gatherer(withoutLabel(g, "mode")).
+		Grouping("mode", mode)
+	return &Pusher{p: p}
+}
+
+// withoutLabel returns a gatherer of the metrics of g with the label name
+// removed. Pushes are rejected if a metric has a grouping label, which the
+// Pushgateway adds to every metric of the group anyway (e.g. the mode label
+// of tct_build_info).
+func withoutLabel(g prometheus.Gatherer, name string) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, mf := range families {
+			for _, m := range mf.GetMetric() {
+				// Copy the labels, which may be shared with the metric
+				m.Label = slices.DeleteFunc(slices.Clone(m.GetLabel()), func(l *dto.LabelPair) bool {
+					return l.GetName() == name
+				})
+			}
+		}
+		return families, err
+	})
+}
+
+// Run pushes metrics every interval until the context is cancelled.
+// Failed pushes are logged and retried on the next interval.
+func (p *Pusher) Run(ctx context.Context, interval time.Duration, log *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Push(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("metrics push failed", "error", err)
+			}
+		}
+	}
+}
+
+// Push pushes the current metrics once.
+func (p *Pusher) Push(ctx context.Context) error {
+	return p.p.PushContext(ctx)
+}
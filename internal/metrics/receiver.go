@@ -1,6 +1,10 @@
 package metrics
 
 import (
+	"context"
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -8,14 +12,49 @@ import (
 // ReceiverMetrics holds all Prometheus metrics for receiver mode.
 type ReceiverMetrics struct {
 	RequestsTotal *prometheus.CounterVec
-	HandlerTime   prometheus.Histogram
+	HandlerTime   *prometheus.HistogramVec
 	OutageState   prometheus.Gauge
+	CertFault     prometheus.Gauge
+	ClientAuthErr *prometheus.CounterVec
+	Duplicates    *prometheus.CounterVec
+	ScenarioPhase *prometheus.GaugeVec
+	ScheduleWin   *prometheus.GaugeVec
+	Goaways       *prometheus.CounterVec
+	ResponseBytes *prometheus.CounterVec
+	BurstState    prometheus.Gauge
+	Profiles      *prometheus.CounterVec
+	UpstreamTotal *prometheus.CounterVec
+	UpstreamTime  *prometheus.HistogramVec
+	ConnCloses    *prometheus.CounterVec
+	KeepAlive     prometheus.Gauge
+	ShedTotal     *prometheus.CounterVec
+	CPUUsage      prometheus.Gauge
+	BrownoutConns *prometheus.CounterVec
+	SizeRules     *prometheus.CounterVec
+	HungRequests  prometheus.Gauge
+	Inflight      prometheus.Gauge
+	Reloads       *prometheus.CounterVec
+	Phases        *prometheus.GaugeVec
+	TransitTime   *prometheus.HistogramVec
+	TransitSkew   *prometheus.CounterVec
+	RequestSize   *prometheus.HistogramVec
+	InjectedDelay *prometheus.SummaryVec
+	Panics        *prometheus.CounterVec
+	Folded        *prometheus.CounterVec
+
+	phaseNotifier
+
+	profiles  *labelGuard
+	sizeRules *labelGuard
+	timings   *StatsD
 }
 
-// NewReceiverMetrics creates and registers receiver metrics with Prometheus.
-func NewReceiverMetrics() *ReceiverMetrics {
-	return &ReceiverMetrics{
-		RequestsTotal: promauto.NewCounterVec(
+// NewReceiverMetrics creates receiver metrics with opts and registers them
+// with reg.
+func NewReceiverMetrics(reg prometheus.Registerer, opts Options) *ReceiverMetrics {
+	f := promauto.With(reg)
+	m := &ReceiverMetrics{
+		RequestsTotal: f.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "tct_receiver_requests_total",
 				Help: "Total number of received requests by outcome",
@@ -23,28 +62,249 @@ func NewReceiverMetrics() *ReceiverMetrics {
 			[]string{"outcome"},
 		),
 
-		HandlerTime: promauto.NewHistogram(prometheus.HistogramOpts{
-			Name: "tct_receiver_handler_time_seconds",
-			Help: "Handler execution time distribution",
-			// Use default buckets: 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10
-		}),
+		HandlerTime: f.NewHistogramVec(
+			opts.histogram(prometheus.HistogramOpts{
+				Name: "tct_receiver_handler_time_seconds",
+				Help: "Handler execution time distribution by injected fault",
+				// Use default buckets: 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10
+			}),
+			[]string{"fault"},
+		),
 
-		OutageState: promauto.NewGauge(prometheus.GaugeOpts{
+		OutageState: f.NewGauge(prometheus.GaugeOpts{
 			Name: "tct_receiver_outage_state",
 			Help: "Current outage state (0=normal, 1=outage)",
 		}),
+
+		CertFault: f.NewGauge(prometheus.GaugeOpts{
+			Name: "tct_receiver_cert_fault_state",
+			Help: "Current certificate fault state (0=valid certificate, 1=faulty certificate)",
+		}),
+
+		ClientAuthErr: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_client_auth_failures_total",
+				Help: "Total number of TLS connections rejected by client certificate verification",
+			},
+			[]string{"reason"},
+		),
+
+		Duplicates: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_duplicates_total",
+				Help: "Total number of duplicate deliveries detected by idempotency key",
+			},
+			nil,
+		),
+
+		ScenarioPhase: f.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tct_receiver_scenario_phase",
+				Help: "Currently active scenario phase (1=active, 0=inactive)",
+			},
+			[]string{"phase"},
+		),
+
+		ScheduleWin: f.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tct_receiver_schedule_window",
+				Help: "Currently open schedule windows (1=open, 0=closed)",
+			},
+			[]string{"window"},
+		),
+
+		Goaways: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_goaway_total",
+				Help: "Total number of injected HTTP/2 GOAWAY frames",
+			},
+			nil,
+		),
+
+		ResponseBytes: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_response_bytes_total",
+				Help: "Total number of response body bytes sent by content encoding",
+			},
+			[]string{"encoding"},
+		),
+
+		BurstState: f.NewGauge(prometheus.GaugeOpts{
+			Name: "tct_receiver_burst_state",
+			Help: "Current burst error model state (0=good, 1=bad)",
+		}),
+
+		Profiles: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_profile_requests_total",
+				Help: "Total number of requests by selected behavior profile",
+			},
+			[]string{"profile"},
+		),
+
+		UpstreamTotal: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_upstream_requests_total",
+				Help: "Total number of upstream calls by result",
+			},
+			[]string{"result"},
+		),
+
+		UpstreamTime: f.NewHistogramVec(
+			opts.histogram(prometheus.HistogramOpts{
+				Name: "tct_receiver_upstream_time_seconds",
+				Help: "Upstream call latency distribution",
+				// Use default buckets: 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10
+			}),
+			nil,
+		),
+
+		ConnCloses: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_conn_close_total",
+				Help: "Total number of responses with injected Connection: close",
+			},
+			nil,
+		),
+
+		KeepAlive: f.NewGauge(prometheus.GaugeOpts{
+			Name: "tct_receiver_keepalive_state",
+			Help: "Current keep-alive state (0=disabled, 1=enabled)",
+		}),
+
+		ShedTotal: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_shed_total",
+				Help: "Total number of requests shed due to resource thresholds by reason",
+			},
+			[]string{"reason"},
+		),
+
+		CPUUsage: f.NewGauge(prometheus.GaugeOpts{
+			Name: "tct_receiver_cpu_usage",
+			Help: "Process CPU usage as a fraction of total capacity (sampled when load shedding is enabled)",
+		}),
+
+		BrownoutConns: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_brownout_connections_total",
+				Help: "Total number of accepted connections by brownout decision",
+			},
+			[]string{"affected"},
+		),
+
+		SizeRules: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_size_rule_total",
+				Help: "Total number of requests matched by a body size rule",
+			},
+			[]string{"rule"},
+		),
+
+		HungRequests: f.NewGauge(prometheus.GaugeOpts{
+			Name: "tct_receiver_hung_requests",
+			Help: "Number of requests currently held without a response (hangs and outages)",
+		}),
+
+		Inflight: f.NewGauge(prometheus.GaugeOpts{
+			Name: "tct_receiver_inflight",
+			Help: "Number of currently executing traffic handlers (including held requests)",
+		}),
+
+		Reloads: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_config_reloads_total",
+				Help: "Total number of configuration reload attempts by result",
+			},
+			[]string{"result"},
+		),
+
+		Phases: newPhaseGauge(f, "receiver"),
+
+		TransitTime: f.NewHistogramVec(
+			opts.histogram(prometheus.HistogramOpts{
+				Name: "tct_receiver_transit_time_seconds",
+				Help: "Delay from send (sender clock) to arrival (receiver clock), i.e. network and queueing; subject to clock skew",
+				// Use default buckets: 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10
+			}),
+			nil,
+		),
+
+		TransitSkew: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_transit_skew_total",
+				Help: "Total number of requests sent after their arrival by the receiver clock (clock skew)",
+			},
+			nil,
+		),
+
+		RequestSize: f.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "tct_receiver_request_size_bytes",
+				Help:    "Request body size distribution by outcome",
+				Buckets: sizeBuckets,
+			},
+			[]string{"outcome"},
+		),
+
+		InjectedDelay: f.NewSummaryVec(
+			prometheus.SummaryOpts{
+				Name: "tct_receiver_injected_delay_seconds",
+				Help: "Response delay actually injected (after jitter and size rules) by outcome; sum/count is the mean injected delay",
+			},
+			[]string{"outcome"},
+		),
+
+		Panics: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_receiver_panics_total",
+				Help: "Total number of handler panics caught by the recovery middleware",
+			},
+			nil,
+		),
+
+		Folded: newFoldedCounter(f, "receiver"),
+
+		timings: opts.StatsD,
 	}
+	m.profiles = newLabelGuard(m.Folded, "profile", opts.LabelLimit)
+	m.sizeRules = newLabelGuard(m.Folded, "rule", opts.LabelLimit)
+	m.initUnlabeled()
+
+	// Keep-alives start enabled
+	m.KeepAlive.Set(1)
+
+	return m
 }
 
 // RecordRequest increments the request counter for the specified outcome.
-// Valid outcomes: "ok", "error", "hang", "outage"
+// Valid outcomes: "ok", "error", "hang", "outage", "redirect", "slow_read", "duplicate", "reset", "panic", "upstream_error", "shed", "too_large", "invalid", "bad_request", "not_modified"
 func (m *ReceiverMetrics) RecordRequest(outcome string) {
 	m.RequestsTotal.WithLabelValues(outcome).Inc()
 }
 
-// ObserveHandlerTime records handler execution time in seconds.
-func (m *ReceiverMetrics) ObserveHandlerTime(seconds float64) {
-	m.HandlerTime.Observe(seconds)
+// ObserveHandlerTime records handler execution time in seconds for the
+// injected fault responsible for the outcome. An empty fault is recorded
+// as "none". Valid faults: any request outcome except "ok", and "delay".
+// The trace of the request in ctx, if sampled, is attached as exemplar.
+func (m *ReceiverMetrics) ObserveHandlerTime(ctx context.Context, fault string, seconds float64) {
+	if fault == "" {
+		fault = "none"
+	}
+	observe(ctx, m.HandlerTime.WithLabelValues(fault), seconds)
+	m.timings.timing("tct_receiver_handler_time_seconds", seconds, "fault", fault)
+}
+
+// ObserveRequestSize records the body size of a request with the outcome.
+func (m *ReceiverMetrics) ObserveRequestSize(outcome string, bytes int64) {
+	m.RequestSize.WithLabelValues(outcome).Observe(float64(bytes))
+}
+
+// ObserveInjectedDelay records the response delay injected into a request
+// with the outcome, including requests without delay, so the mean injected
+// delay can be reconciled with the configured one.
+func (m *ReceiverMetrics) ObserveInjectedDelay(outcome string, d time.Duration) {
+	m.InjectedDelay.WithLabelValues(outcome).Observe(d.Seconds())
 }
 
 // SetOutageState sets the outage state gauge.
@@ -55,4 +315,201 @@ func (m *ReceiverMetrics) SetOutageState(active bool) {
 	} else {
 		m.OutageState.Set(0)
 	}
+	m.setPhase(m.Phases, "outage", "outage", active)
+}
+
+// SetCertFaultState sets the certificate fault state gauge.
+// Use 0 for the valid certificate, 1 while a faulty certificate is served.
+func (m *ReceiverMetrics) SetCertFaultState(active bool) {
+	if active {
+		m.CertFault.Set(1)
+	} else {
+		m.CertFault.Set(0)
+	}
+}
+
+// RecordClientAuthFailure increments the client authentication failure counter.
+// Valid reasons: "missing", "invalid"
+func (m *ReceiverMetrics) RecordClientAuthFailure(reason string) {
+	m.ClientAuthErr.WithLabelValues(reason).Inc()
+}
+
+// RecordDuplicate increments the duplicate delivery counter.
+func (m *ReceiverMetrics) RecordDuplicate() {
+	m.Duplicates.WithLabelValues().Inc()
+}
+
+// SetScenarioPhase sets the active state of a scenario phase.
+func (m *ReceiverMetrics) SetScenarioPhase(phase string, active bool) {
+	if active {
+		m.ScenarioPhase.WithLabelValues(phase).Set(1)
+	} else {
+		m.ScenarioPhase.WithLabelValues(phase).Set(0)
+	}
+	m.setPhase(m.Phases, "scenario", phase, active)
+}
+
+// SetScheduleWindow sets the open state of a schedule window.
+func (m *ReceiverMetrics) SetScheduleWindow(window string, open bool) {
+	if open {
+		m.ScheduleWin.WithLabelValues(window).Set(1)
+	} else {
+		m.ScheduleWin.WithLabelValues(window).Set(0)
+	}
+	m.setPhase(m.Phases, "schedule", window, open)
+}
+
+// RecordGoaway increments the injected GOAWAY counter.
+func (m *ReceiverMetrics) RecordGoaway() {
+	m.Goaways.WithLabelValues().Inc()
+}
+
+// AddResponseBytes adds n to the response bytes counter for the encoding.
+// An empty encoding is recorded as "identity".
+func (m *ReceiverMetrics) AddResponseBytes(encoding string, n int) {
+	if encoding == "" {
+		encoding = "identity"
+	}
+	m.ResponseBytes.WithLabelValues(encoding).Add(float64(n))
+}
+
+// SetBurstState sets the burst error model state gauge.
+// Use 0 for the good state, 1 for the bad state.
+func (m *ReceiverMetrics) SetBurstState(bad bool) {
+	if bad {
+		m.BurstState.Set(1)
+	} else {
+		m.BurstState.Set(0)
+	}
+}
+
+// RecordProfile increments the request counter for the selected profile.
+// Profiles beyond the label limit are recorded as "other".
+func (m *ReceiverMetrics) RecordProfile(profile string) {
+	m.Profiles.WithLabelValues(m.profiles.value(profile)).Inc()
+}
+
+// RecordUpstream increments the upstream call counter for the result and
+// records the call latency in seconds with the trace in ctx as exemplar.
+// Valid results: "ok", "timeout", "http_5xx", "error"
+func (m *ReceiverMetrics) RecordUpstream(ctx context.Context, result string, seconds float64) {
+	m.UpstreamTotal.WithLabelValues(result).Inc()
+	observe(ctx, m.UpstreamTime.WithLabelValues(), seconds)
+	m.timings.timing("tct_receiver_upstream_time_seconds", seconds)
+}
+
+// RecordConnClose increments the injected connection close counter.
+func (m *ReceiverMetrics) RecordConnClose() {
+	m.ConnCloses.WithLabelValues().Inc()
+}
+
+// SetKeepAliveState sets the keep-alive state gauge.
+// Use 1 while keep-alives are enabled, 0 while disabled.
+func (m *ReceiverMetrics) SetKeepAliveState(enabled bool) {
+	if enabled {
+		m.KeepAlive.Set(1)
+	} else {
+		m.KeepAlive.Set(0)
+	}
+}
+
+// RecordShed increments the shed counter for the specified reason.
+// Valid reasons: "cpu", "goroutines"
+func (m *ReceiverMetrics) RecordShed(reason string) {
+	m.ShedTotal.WithLabelValues(reason).Inc()
+}
+
+// SetCPUUsage sets the sampled process CPU usage gauge.
+func (m *ReceiverMetrics) SetCPUUsage(usage float64) {
+	m.CPUUsage.Set(usage)
+}
+
+// RecordBrownoutConn increments the brownout connection counter for the
+// accept-time decision.
+func (m *ReceiverMetrics) RecordBrownoutConn(affected bool) {
+	m.BrownoutConns.WithLabelValues(strconv.FormatBool(affected)).Inc()
+}
+
+// RecordSizeRule increments the size rule counter for the matched rule.
+// Rules beyond the label limit are recorded as "other".
+func (m *ReceiverMetrics) RecordSizeRule(rule string) {
+	m.SizeRules.WithLabelValues(m.sizeRules.value(rule)).Inc()
+}
+
+// SetHungRequests sets the number of currently held requests.
+func (m *ReceiverMetrics) SetHungRequests(n int) {
+	m.HungRequests.Set(float64(n))
+}
+
+// initUnlabeled creates the series of the counters and histograms without
+// labels, so they are exposed at zero before the first observation. These
+// are vectors without labels so Reset can reset them.
+func (m *ReceiverMetrics) initUnlabeled() {
+	for _, c := range []*prometheus.CounterVec{m.Duplicates, m.Goaways, m.ConnCloses, m.TransitSkew, m.Panics} {
+		c.WithLabelValues()
+	}
+	for _, h := range []*prometheus.HistogramVec{m.UpstreamTime, m.TransitTime} {
+		h.WithLabelValues()
+	}
+}
+
+// Reset resets all counters and histograms, so a following experiment
+// starts from zero. Gauges reflecting current state are kept.
+func (m *ReceiverMetrics) Reset() {
+	m.RequestsTotal.Reset()
+	m.HandlerTime.Reset()
+	m.ClientAuthErr.Reset()
+	m.ResponseBytes.Reset()
+	m.Profiles.Reset()
+	m.UpstreamTotal.Reset()
+	m.ShedTotal.Reset()
+	m.BrownoutConns.Reset()
+	m.SizeRules.Reset()
+	m.Reloads.Reset()
+	m.RequestSize.Reset()
+	m.InjectedDelay.Reset()
+	m.Duplicates.Reset()
+	m.Goaways.Reset()
+	m.ConnCloses.Reset()
+	m.TransitSkew.Reset()
+	m.Panics.Reset()
+	m.UpstreamTime.Reset()
+	m.TransitTime.Reset()
+	m.initUnlabeled()
+	m.profiles.reset()
+	m.sizeRules.reset()
+}
+
+// ObserveTransit records the delay from send to arrival of a request with
+// the trace in ctx as exemplar. Negative delays, which can only result from
+// clock skew, are counted instead.
+func (m *ReceiverMetrics) ObserveTransit(ctx context.Context, d time.Duration) {
+	if d < 0 {
+		m.TransitSkew.WithLabelValues().Inc()
+		return
+	}
+	observe(ctx, m.TransitTime.WithLabelValues(), d.Seconds())
+}
+
+// InflightInc increments the in-flight handler counter.
+// Call this when a traffic handler starts.
+func (m *ReceiverMetrics) InflightInc() {
+	m.Inflight.Inc()
+}
+
+// InflightDec decrements the in-flight handler counter.
+// Call this when a traffic handler returns.
+func (m *ReceiverMetrics) InflightDec() {
+	m.Inflight.Dec()
+}
+
+// RecordPanic increments the handler panic counter.
+func (m *ReceiverMetrics) RecordPanic() {
+	m.Panics.WithLabelValues().Inc()
+}
+
+// RecordReload increments the configuration reload counter.
+// Valid results: "ok", "error"
+func (m *ReceiverMetrics) RecordReload(result string) {
+	m.Reloads.WithLabelValues(result).Inc()
 }
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"google.golang.org/protobuf/proto"
+)
+
+// RemoteGatherer gathers the metrics exposed by a running tct instance, so
+// its request metrics can be summarized by Stats from another process.
+type RemoteGatherer struct {
+	url       string
+	namespace string
+	client    *http.Client
+}
+
+// NewRemoteGatherer creates a gatherer for the /metrics endpoint at url of
+// an instance exposing metrics in namespace (see Exposition). Names are
+// gathered with the tct prefix.
+func NewRemoteGatherer(url, namespace string) *RemoteGatherer {
+	return &RemoteGatherer{url: url, namespace: namespace, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Gather implements prometheus.Gatherer by scraping the endpoint.
+func (g *RemoteGatherer) Gather() ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequest(http.MethodGet, g.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to scrape %s: %s", g.url, resp.Status)
+	}
+
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics of %s: %w", g.url, err)
+	}
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	out := make([]*dto.MetricFamily, len(names))
+	for i, name := range names {
+		out[i] = families[name]
+		if rest, ok := strings.CutPrefix(name, g.namespace+"_"); ok {
+			out[i].Name = proto.String("tct_" + rest)
+		}
+	}
+	return out, nil
+}
+
+// Mode returns the mode of the instance ("sender" or "receiver") judging by
+// its request metrics, or an error if it exposes neither.
+func (g *RemoteGatherer) Mode() (string, error) {
+	families, err := g.Gather()
+	if err != nil {
+		return "", err
+	}
+	for _, mf := range families {
+		for _, mode := range []string{"sender", "receiver"} {
+			if strings.HasPrefix(mf.GetName(), "tct_"+mode+"_requests_total") {
+				return mode, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no sender or receiver request metrics at %s", g.url)
+}
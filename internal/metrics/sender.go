@@ -1,61 +1,154 @@
 package metrics
 
 import (
+	"context"
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 // SenderMetrics holds all Prometheus metrics for sender mode.
 type SenderMetrics struct {
-	RequestsOk   prometheus.Counter
-	RequestsErr  *prometheus.CounterVec
-	ResponseTime prometheus.Histogram
+	Requests     *prometheus.CounterVec
+	ResponseTime *prometheus.HistogramVec
 	Inflight     prometheus.Gauge
-}
+	Phase        *prometheus.GaugeVec
+	Phases       *prometheus.GaugeVec
+	ResponseSize *prometheus.HistogramVec
+	Spawned      *prometheus.CounterVec
+	Folded       *prometheus.CounterVec
+	Reloads      *prometheus.CounterVec
 
-// NewSenderMetrics creates and registers sender metrics with Prometheus.
-func NewSenderMetrics() *SenderMetrics {
-	return &SenderMetrics{
-		RequestsOk: promauto.NewCounter(prometheus.CounterOpts{
-			Name: "tct_sender_requests_ok_total",
-			Help: "Total number of successful requests (HTTP 200)",
-		}),
+	phaseNotifier
+
+	targets *labelGuard
+	timings *StatsD
+}
 
-		RequestsErr: promauto.NewCounterVec(
+// NewSenderMetrics creates sender metrics with opts and registers them with
+// reg.
+func NewSenderMetrics(reg prometheus.Registerer, opts Options) *SenderMetrics {
+	f := promauto.With(reg)
+	m := &SenderMetrics{
+		Requests: f.NewCounterVec(
 			prometheus.CounterOpts{
-				Name: "tct_sender_requests_err_total",
-				Help: "Total number of failed requests by error class",
+				Name: "tct_sender_requests_total",
+				Help: "Total number of requests by target, result, and status code class",
 			},
-			[]string{"class"},
+			[]string{"target", "result", "status_code"},
 		),
 
-		ResponseTime: promauto.NewHistogram(prometheus.HistogramOpts{
-			Name: "tct_sender_response_time_seconds",
-			Help: "HTTP request latency distribution",
-			// Use default buckets: 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10
-		}),
+		ResponseTime: f.NewHistogramVec(
+			opts.histogram(prometheus.HistogramOpts{
+				Name: "tct_sender_response_time_seconds",
+				Help: "HTTP request latency distribution by target",
+				// Use default buckets: 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10
+			}),
+			[]string{"target"},
+		),
 
-		Inflight: promauto.NewGauge(prometheus.GaugeOpts{
+		Inflight: f.NewGauge(prometheus.GaugeOpts{
 			Name: "tct_sender_inflight",
 			Help: "Number of currently in-flight requests",
 		}),
+
+		Phase: f.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tct_sender_scenario_phase",
+				Help: "Currently active scenario phase (1=active, 0=inactive)",
+			},
+			[]string{"phase"},
+		),
+
+		Phases: newPhaseGauge(f, "sender"),
+
+		Spawned: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_sender_request_goroutines_total",
+				Help: "Total number of request goroutines spawned by the generator",
+			},
+			nil, // without labels, but resettable
+		),
+
+		ResponseSize: f.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "tct_sender_response_size_bytes",
+				Help:    "Response body size distribution by target",
+				Buckets: sizeBuckets,
+			},
+			[]string{"target"},
+		),
+
+		Folded: newFoldedCounter(f, "sender"),
+
+		Reloads: f.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tct_sender_config_reloads_total",
+				Help: "Total number of configuration reload attempts by result",
+			},
+			[]string{"result"},
+		),
+
+		timings: opts.StatsD,
+	}
+	m.targets = newLabelGuard(m.Folded, "target", opts.LabelLimit)
+	m.Spawned.WithLabelValues()
+
+	return m
+}
+
+// RecordRequest increments the request counter for the target (host:port),
+// the result, and the class of the response status (0 if no response was
+// received). Targets beyond the label limit are recorded as "other".
+// Valid results: "ok", "http_500", "redirect", "tls_error", "dns_error",
+// "connect_refused", "connect_timeout", "read_timeout", "eof", "conn",
+// "other". Request timeouts are "connect_timeout" or "read_timeout"; the
+// former "timeout" result is gone, as requests are no longer cut short at
+// shutdown (those cancelled without a request timeout are not recorded).
+func (m *SenderMetrics) RecordRequest(target, result string, status int) {
+	m.Requests.WithLabelValues(m.targets.value(target), result, statusClass(status)).Inc()
+}
+
+// statusClass returns the bounded label value for a status code: "2xx" to
+// "5xx", or "none" if no response was received or the code is invalid.
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "none"
 	}
+	return strconv.Itoa(status/100) + "xx"
 }
 
-// RecordSuccess increments the success counter.
-func (m *SenderMetrics) RecordSuccess() {
-	m.RequestsOk.Inc()
+// ObserveResponseTime records a request latency in seconds for the target
+// (host:port). The trace of the request in ctx, if sampled, is attached as
+// exemplar.
+func (m *SenderMetrics) ObserveResponseTime(ctx context.Context, target string, seconds float64) {
+	target = m.targets.value(target)
+	observe(ctx, m.ResponseTime.WithLabelValues(target), seconds)
+	m.timings.timing("tct_sender_response_time_seconds", seconds, "target", target)
 }
 
-// RecordError increments the error counter for the specified class.
-// Valid classes: "timeout", "http_500", "conn", "other"
-func (m *SenderMetrics) RecordError(class string) {
-	m.RequestsErr.WithLabelValues(class).Inc()
+// Reset resets all counters and histograms, so a following experiment
+// starts from zero. Gauges reflecting current state are kept.
+func (m *SenderMetrics) Reset() {
+	m.Requests.Reset()
+	m.ResponseTime.Reset()
+	m.ResponseSize.Reset()
+	m.Spawned.Reset()
+	m.Spawned.WithLabelValues()
+	m.Reloads.Reset()
+	m.targets.reset()
 }
 
-// ObserveResponseTime records a request latency in seconds.
-func (m *SenderMetrics) ObserveResponseTime(seconds float64) {
-	m.ResponseTime.Observe(seconds)
+// ObserveResponseSize records the body size of a response for the target
+// (host:port).
+func (m *SenderMetrics) ObserveResponseSize(target string, bytes int64) {
+	m.ResponseSize.WithLabelValues(m.targets.value(target)).Observe(float64(bytes))
+}
+
+// RecordSpawn increments the spawned request goroutine counter.
+func (m *SenderMetrics) RecordSpawn() {
+	m.Spawned.WithLabelValues().Inc()
 }
 
 // InflightInc increments the in-flight request counter.
@@ -69,3 +162,24 @@ func (m *SenderMetrics) InflightInc() {
 func (m *SenderMetrics) InflightDec() {
 	m.Inflight.Dec()
 }
+
+// SetScenarioPhase sets the active state of a scenario phase.
+func (m *SenderMetrics) SetScenarioPhase(phase string, active bool) {
+	if active {
+		m.Phase.WithLabelValues(phase).Set(1)
+	} else {
+		m.Phase.WithLabelValues(phase).Set(0)
+	}
+	m.setPhase(m.Phases, "scenario", phase, active)
+}
+
+// RecordReload increments the configuration reload counter.
+// Valid results: "ok", "error"
+func (m *SenderMetrics) RecordReload(result string) {
+	m.Reloads.WithLabelValues(result).Inc()
+}
+
+// SetRateStep sets the active state of a rate schedule step.
+func (m *SenderMetrics) SetRateStep(step string, active bool) {
+	m.setPhase(m.Phases, "rate_schedule", step, active)
+}
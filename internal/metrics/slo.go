@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterBurnRates registers the gauges tct_<mode>_slo_burn_rate{window}
+// reporting the error budget burn rate computed by burnRate for each
+// window at scrape time.
+func RegisterBurnRates(reg prometheus.Registerer, mode string, windows []time.Duration, burnRate func(window time.Duration) float64) error {
+	for _, window := range windows {
+		err := reg.Register(prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Name:        "tct_" + mode + "_slo_burn_rate",
+				Help:        "Error budget burn rate over the window (1 spends the budget exactly over the SLO period)",
+				ConstLabels: prometheus.Labels{"window": windowLabel(window)},
+			},
+			func() float64 { return burnRate(window) },
+		))
+		if err != nil {
+			return fmt.Errorf("failed to register burn rate of window %s: %w", window, err)
+		}
+	}
+	return nil
+}
+
+// windowLabel formats a window compactly (5m, 1h, 1h30m).
+func windowLabel(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
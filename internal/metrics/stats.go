@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/neox5/tct/internal/logger"
+)
+
+// Stats summarizes the request metrics of a registry over fixed intervals,
+// so the basics are visible in logs without a metrics backend.
+type Stats struct {
+	g        prometheus.Gatherer
+	requests string // counter of requests by result
+	result   string // result label of requests
+	latency  string // latency histogram
+	prev     statsSnapshot
+	prevTime time.Time
+
+	mu    sync.Mutex
+	start time.Time // start of the Total period
+}
+
+// Summary summarizes the requests of a period. Latency percentiles are
+// estimated from the histogram buckets. P99Overflow is set if the p99 lies
+// above the largest bucket bound, so P99Ms is only a lower bound.
+type Summary struct {
+	Seconds     float64            `json:"seconds"`
+	Requests    float64            `json:"requests"`
+	RPS         float64            `json:"rps"`
+	Results     map[string]float64 `json:"results"`
+	P50Ms       float64            `json:"p50_ms"`
+	P90Ms       float64            `json:"p90_ms"`
+	P99Ms       float64            `json:"p99_ms"`
+	P99Overflow bool               `json:"p99_overflow,omitempty"`
+}
+
+// statsSnapshot holds the cumulative request counts and latency buckets.
+type statsSnapshot struct {
+	results map[string]float64  // requests by result
+	buckets map[float64]float64 // cumulative count by upper bound
+	count   float64             // latency observations
+}
+
+// NewSenderStats creates a summary of the sender metrics in g.
+func NewSenderStats(g prometheus.Gatherer) *Stats {
+	return &Stats{g: g, requests: "tct_sender_requests_total", result: "result", latency: "tct_sender_response_time_seconds", start: time.Now()}
+}
+
+// NewReceiverStats creates a summary of the receiver metrics in g.
+func NewReceiverStats(g prometheus.Gatherer) *Stats {
+	return &Stats{g: g, requests: "tct_receiver_requests_total", result: "outcome", latency: "tct_receiver_handler_time_seconds", start: time.Now()}
+}
+
+// Run logs a summary of every interval until the context is cancelled:
+// requests and rate, non-ok results by class, and p50/p99 latency estimated
+// from the histogram buckets.
+func (s *Stats) Run(ctx context.Context, interval time.Duration, log *logger.Logger) {
+	s.Window()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sum, err := s.Window()
+			if err != nil {
+				log.Warn("stats summary failed", "error", err)
+				continue
+			}
+
+			errs := map[string]float64{}
+			for result, n := range sum.Results {
+				if result != "ok" {
+					errs[result] = n
+				}
+			}
+			log.Info("stats",
+				"window", interval,
+				"requests", sum.Requests,
+				"rps", sum.RPS,
+				"errors", errs,
+				"p50_ms", sum.P50Ms,
+				"p99_ms", sum.P99Ms,
+			)
+		}
+	}
+}
+
+// Window summarizes the requests since the previous call (since the stats
+// were created on the first call). Not safe for concurrent use.
+func (s *Stats) Window() (Summary, error) {
+	cur, err := s.snapshot()
+	if err != nil {
+		return Summary{}, err
+	}
+	now := time.Now()
+	if s.prevTime.IsZero() {
+		s.prevTime = s.start
+	}
+	sum := summarize(s.prev, cur, now.Sub(s.prevTime))
+	s.prev, s.prevTime = cur, now
+	return sum, nil
+}
+
+// Total summarizes all requests since the stats were created or last reset.
+func (s *Stats) Total() (Summary, error) {
+	cur, err := s.snapshot()
+	if err != nil {
+		return Summary{}, err
+	}
+	s.mu.Lock()
+	start := s.start
+	s.mu.Unlock()
+	return summarize(statsSnapshot{}, cur, time.Since(start)), nil
+}
+
+// Reset restarts the Total period. Call it when resetting the metrics.
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.start = time.Now()
+}
+
+// summarize summarizes the requests between two snapshots a period apart.
+// Results without requests in the period are omitted. If the metrics were
+// reset in between, the period is summarized from the reset on.
+func summarize(prev, cur statsSnapshot, period time.Duration) Summary {
+	if cur.count < prev.count {
+		prev = statsSnapshot{}
+	}
+	sum := Summary{Seconds: math.Round(period.Seconds()*100) / 100, Results: map[string]float64{}}
+	for result, n := range cur.results {
+		if delta := n - prev.results[result]; delta > 0 {
+			sum.Results[result] = delta
+			sum.Requests += delta
+		}
+	}
+	if period > 0 {
+		sum.RPS = math.Round(sum.Requests/period.Seconds()*100) / 100
+	}
+
+	buckets := map[float64]float64{}
+	for le, n := range cur.buckets {
+		buckets[le] = n - prev.buckets[le]
+	}
+	count := cur.count - prev.count
+	sum.P50Ms, _ = quantileMs(0.5, buckets, count)
+	sum.P90Ms, _ = quantileMs(0.9, buckets, count)
+	sum.P99Ms, sum.P99Overflow = quantileMs(0.99, buckets, count)
+	return sum
+}
+
+// snapshot gathers the current cumulative values, summed over all other
+// labels.
+func (s *Stats) snapshot() (statsSnapshot, error) {
+	snap := statsSnapshot{results: map[string]float64{}, buckets: map[float64]float64{}}
+	families, err := s.g.Gather()
+	if err != nil {
+		return snap, err
+	}
+	for _, mf := range families {
+		switch mf.GetName() {
+		case s.requests:
+			for _, m := range mf.GetMetric() {
+				snap.results[labelValue(m, s.result)] += m.GetCounter().GetValue()
+			}
+		case s.latency:
+			for _, m := range mf.GetMetric() {
+				h := m.GetHistogram()
+				snap.count += float64(h.GetSampleCount())
+				for _, b := range h.GetBucket() {
+					snap.buckets[b.GetUpperBound()] += float64(b.GetCumulativeCount())
+				}
+			}
+		}
+	}
+	return snap, nil
+}
+
+// quantileMs estimates the q-quantile in ms from cumulative bucket counts by
+// linear interpolation within the bucket (as histogram_quantile does).
+// Returns 0 without observations. If the quantile falls into the overflow
+// bucket, it returns the largest bound and overflow is set.
+func quantileMs(q float64, buckets map[float64]float64, count float64) (ms float64, overflow bool) {
+	if count <= 0 {
+		return 0, false
+	}
+	rank := q * count
+	var lower, below float64
+	for _, le := range sortedBounds(buckets) {
+		if n := buckets[le]; n >= rank {
+			v := lower
+			if n > below {
+				v += (le - lower) * (rank - below) / (n - below)
+			}
+			return math.Round(v*1e6) / 1e3, false
+		}
+		lower, below = le, buckets[le]
+	}
+	return math.Round(lower*1e6) / 1e3, true
+}
+
+// sortedBounds returns the bucket upper bounds in ascending order.
+func sortedBounds(buckets map[float64]float64) []float64 {
+	bounds := make([]float64, 0, len(buckets))
+	for le := range buckets {
+		bounds = append(bounds, le)
+	}
+	slices.Sort(bounds)
+	return bounds
+}
+
+// labelValue returns the value of the named label of m.
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
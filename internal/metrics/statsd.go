@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/neox5/tct/internal/logger"
+)
+
+// statsdPacketSize is the maximum UDP payload, kept below common MTUs.
+const statsdPacketSize = 1432
+
+// StatsD emits metrics to a StatsD or DogStatsD agent over UDP. Counters are
+// sent as increments and gauges as values on every flush; latency
+// observations are sent as timings (ms) as they happen. DogStatsD carries
+// labels as tags; plain StatsD appends label values to the metric name.
+type StatsD struct {
+	g    prometheus.Gatherer
+	expo *Exposition
+	conn net.Conn
+	tags bool
+
+	mu   sync.Mutex
+	last map[string]float64 // counter values at the last flush by series
+}
+
+// NewStatsD creates an emitter of the metrics gathered by g, as exposed by
+// expo, sending to the agent at addr (host:port). Latency observations of
+// metric sets created with it in their Options are also sent as timings.
+func NewStatsD(g prometheus.Gatherer, expo *Exposition, addr string, dogstatsd bool) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD agent: %w", err)
+	}
+	return &StatsD{g: expo.Exposed(g), expo: expo, conn: conn, tags: dogstatsd, last: map[string]float64{}}, nil
+}
+
+// Run flushes counters and gauges every interval until the context is
+// cancelled.
+func (s *StatsD) Run(ctx context.Context, interval time.Duration, log *logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Warn("statsd flush failed", "error", err)
+			}
+		}
+	}
+}
+
+// Flush sends counter increments since the last flush and current gauge
+// values of all metrics.
+func (s *StatsD) Flush() error {
+	families, err := s.g.Gather()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lines []string
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			series := s.series(mf.GetName(), m.GetLabel())
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				v := m.GetCounter().GetValue()
+				if delta := v - s.last[series.key]; delta > 0 {
+					lines = append(lines, series.line(formatFloat(delta), "c"))
+				}
+				s.last[series.key] = v
+			case dto.MetricType_GAUGE:
+				lines = append(lines, series.line(formatFloat(m.GetGauge().GetValue()), "g"))
+			}
+		}
+	}
+	return s.send(lines)
+}
+
+// Close flushes the remaining counts and closes the connection.
+func (s *StatsD) Close() error {
+	err := s.Flush()
+	if cerr := s.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// timing sends a latency observation in seconds as a timing in ms. labels
+// are name/value pairs. No-op on a nil StatsD.
+func (s *StatsD) timing(name string, seconds float64, labels ...string) {
+	if s == nil {
+		return
+	}
+	pairs := make([]*dto.LabelPair, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, &dto.LabelPair{Name: &labels[i], Value: &labels[i+1]})
+	}
+	pairs = s.expo.withLabels(pairs)
+	line := s.series(s.expo.name(strings.TrimSuffix(name, "_seconds")), pairs).line(formatFloat(seconds*1000), "ms")
+	s.send([]string{line})
+}
+
+// statsdSeries is a metric name with its labels in StatsD notation.
+type statsdSeries struct {
+	key  string // name and labels, unique per series
+	name string
+	tags string // DogStatsD tag suffix, empty for plain StatsD
+}
+
+// series builds the StatsD notation of a metric series.
+func (s *StatsD) series(name string, labels []*dto.LabelPair) statsdSeries {
+	if s.tags {
+		tags := make([]string, len(labels))
+		for i, l := range labels {
+			tags[i] = l.GetName() + ":" + sanitize(l.GetValue())
+		}
+		joined := strings.Join(tags, ",")
+		return statsdSeries{key: name + "|" + joined, name: name, tags: joined}
+	}
+	parts := []string{name}
+	for _, l := range labels {
+		parts = append(parts, sanitize(l.GetValue()))
+	}
+	name = strings.Join(parts, ".")
+	return statsdSeries{key: name, name: name}
+}
+
+// line formats a StatsD line of the given type.
+func (ss statsdSeries) line(value, typ string) string {
+	line := ss.name + ":" + value + "|" + typ
+	if ss.tags != "" {
+		line += "|#" + ss.tags
+	}
+	return line
+}
+
+// send writes lines in as few packets as possible.
+func (s *StatsD) send(lines []string) error {
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > statsdPacketSize {
+			if _, err := s.conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		_, err := s.conn.Write(packet)
+		return err
+	}
+	return nil
+}
+
+// sanitize replaces characters with a meaning in StatsD lines.
+func sanitize(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', ',', '#', '@', '\n', ' ':
+			return '_'
+		}
+		return r
+	}, v)
+}
+
+// formatFloat formats v without trailing zeros.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,29 @@
+// Package payload generates synthetic request and response bodies.
+package payload
+
+import (
+	"github.com/neox5/tct/internal/random"
+)
+
+// alphabet is used for the incompressible part of generated bodies.
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// pattern is repeated for the compressible part of generated bodies.
+const pattern = "tct-payload "
+
+// Generate returns a body of size bytes. compressibility (0..1) is the
+// fraction of the body made of a repeated pattern; the rest is random text
+// that does not compress, drawn from rng.
+func Generate(size int, compressibility float64, rng *random.Rand) []byte {
+	body := make([]byte, size)
+	repeated := int(float64(size) * compressibility)
+
+	for i := 0; i < repeated; i++ {
+		body[i] = pattern[i%len(pattern)]
+	}
+	for i := repeated; i < size; i++ {
+		body[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+
+	return body
+}
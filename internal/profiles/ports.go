@@ -0,0 +1,47 @@
+package profiles
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/neox5/tct/internal/behavior"
+)
+
+// PortLayer applies the profile bound to the local port a request arrived
+// on, so one receiver can present differently behaving endpoints.
+// Requests on unbound ports keep their profile.
+type PortLayer struct {
+	ports map[int]behavior.Override
+}
+
+// NewPortLayer creates a port-keyed profile layer. Every profile name in
+// ports must exist in profiles.
+func NewPortLayer(ports map[int]string, profiles map[string]behavior.Override) (*PortLayer, error) {
+	l := &PortLayer{ports: make(map[int]behavior.Override, len(ports))}
+	for port, name := range ports {
+		o, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("port %d: unknown profile %q", port, name)
+		}
+		l.ports[port] = o
+	}
+	return l, nil
+}
+
+// Apply applies the profile bound to the request's local port.
+// Implements behavior.Layer.
+func (l *PortLayer) Apply(r *http.Request, p behavior.Profile) behavior.Profile {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return p
+	}
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return p
+	}
+	if o, ok := l.ports[tcp.Port]; ok {
+		return o.Apply(p)
+	}
+	return p
+}
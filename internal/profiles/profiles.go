@@ -0,0 +1,89 @@
+// Package profiles provides named behavior profiles selected per request by
+// a header value, so one receiver can serve differentiated fault behavior
+// to multiple senders.
+package profiles
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.yaml.in/yaml/v2"
+
+	"github.com/neox5/tct/internal/behavior"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// File is the on-disk profiles document.
+type File struct {
+	Profiles map[string]behavior.Override `yaml:"profiles"`
+}
+
+// Load reads and validates a profiles file. Both YAML and JSON are accepted.
+//
+// Example:
+//
+//	profiles:
+//	  canary:
+//	    error_rate: 0.2
+//	  baseline:
+//	    error_rate: 0
+func Load(path string) (map[string]behavior.Override, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Parse parses and validates a profiles document read from source.
+func Parse(data []byte, source string) (map[string]behavior.Override, error) {
+	f := &File{}
+	if err := yaml.UnmarshalStrict(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles %s: %w", source, err)
+	}
+
+	for name, o := range f.Profiles {
+		if name == "" {
+			return nil, fmt.Errorf("invalid profiles %s: empty profile name", source)
+		}
+		if err := o.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid profiles %s: profile %q: %w", source, name, err)
+		}
+	}
+
+	return f.Profiles, nil
+}
+
+// Layer selects a profile by request header value.
+// Requests without the header or with an unknown value keep their profile.
+type Layer struct {
+	header   string
+	profiles map[string]behavior.Override
+	m        *metrics.ReceiverMetrics
+}
+
+// NewLayer creates a header-keyed profile layer.
+func NewLayer(header string, profiles map[string]behavior.Override, m *metrics.ReceiverMetrics) *Layer {
+	return &Layer{header: header, profiles: profiles, m: m}
+}
+
+// Apply applies the profile named by the request header and records the
+// selection. Unknown names are recorded as "unknown" to bound cardinality.
+// Implements behavior.Layer.
+func (l *Layer) Apply(r *http.Request, p behavior.Profile) behavior.Profile {
+	name := r.Header.Get(l.header)
+	if name == "" {
+		l.m.RecordProfile("default")
+		return p
+	}
+
+	o, ok := l.profiles[name]
+	if !ok {
+		l.m.RecordProfile("unknown")
+		return p
+	}
+
+	l.m.RecordProfile(name)
+	return o.Apply(p)
+}
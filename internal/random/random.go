@@ -0,0 +1,48 @@
+// Package random provides seeded pseudo-random sources for fault injection.
+// Each component gets its own source so decisions are reproducible for a
+// given seed and components do not contend on a shared lock.
+package random
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+)
+
+// Rand is a pseudo-random source safe for concurrent use.
+type Rand struct {
+	mutex sync.Mutex
+	r     *rand.Rand
+}
+
+// New creates the source for the named component. The stream is derived
+// from seed and name, so the same seed reproduces the same decisions
+// per component.
+func New(seed int64, name string) *Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return &Rand{
+		r: rand.New(rand.NewSource(seed ^ int64(h.Sum64()))),
+	}
+}
+
+// Float64 returns a pseudo-random number in [0.0, 1.0).
+func (r *Rand) Float64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.r.Float64()
+}
+
+// Int63n returns a non-negative pseudo-random number in [0, n). Panics if n <= 0.
+func (r *Rand) Int63n(n int64) int64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.r.Int63n(n)
+}
+
+// Intn returns a non-negative pseudo-random number in [0, n). Panics if n <= 0.
+func (r *Rand) Intn(n int) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.r.Intn(n)
+}
@@ -0,0 +1,177 @@
+// Package scenario provides phased experiments loaded from a file.
+// A scenario is a sequence of named phases on one timeline, each setting the
+// sender request rate and overriding receiver behavior parameters for a
+// fixed duration. Both modes load the same document; each applies its part.
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+
+	"github.com/neox5/tct/internal/behavior"
+	"github.com/neox5/tct/internal/logger"
+)
+
+// Scenario is a sequence of behavior phases.
+type Scenario struct {
+	Name   string  `yaml:"name"`
+	Repeat bool    `yaml:"repeat"`
+	Phases []Phase `yaml:"phases"`
+}
+
+// Phase sets the sender request rate (RPS, nil keeps the configured rate)
+// and overrides receiver behavior parameters for a fixed duration.
+type Phase struct {
+	Name              string        `yaml:"name"`
+	Duration          time.Duration `yaml:"duration"`
+	RPS               *float64      `yaml:"rps"`
+	behavior.Override `yaml:",inline"`
+}
+
+// Load reads and validates a scenario file. Both YAML and JSON are accepted.
+//
+// Example:
+//
+//	name: degrade-and-recover
+//	phases:
+//	  - name: healthy
+//	    duration: 5m
+//	    rps: 10
+//	  - name: degraded
+//	    duration: 2m
+//	    rps: 50
+//	    response_delay: 500ms
+//	    error_rate: 0.2
+//	  - name: outage
+//	    duration: 1m
+//	    outage: true
+//	  - name: recovery
+//	    duration: 5m
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Parse parses and validates a scenario document read from source.
+func Parse(data []byte, source string) (*Scenario, error) {
+	s := &Scenario{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", source, err)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", source, err)
+	}
+
+	return s, nil
+}
+
+// validate checks phase names, durations, and override ranges.
+func (s *Scenario) validate() error {
+	if len(s.Phases) == 0 {
+		return fmt.Errorf("at least one phase is required")
+	}
+
+	for i, p := range s.Phases {
+		if p.Name == "" {
+			return fmt.Errorf("phase %d: name is required", i)
+		}
+		if p.Duration <= 0 {
+			return fmt.Errorf("phase %q: duration must be > 0", p.Name)
+		}
+		if p.RPS != nil && *p.RPS < 0 {
+			return fmt.Errorf("phase %q: rps: must be >= 0, got %v", p.Name, *p.RPS)
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("phase %q: %w", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// PhaseMetrics records the active scenario phase. Implemented by
+// metrics.ReceiverMetrics and metrics.SenderMetrics.
+type PhaseMetrics interface {
+	SetScenarioPhase(phase string, active bool)
+}
+
+// Runner executes a scenario. Receivers apply the active phase as a behavior
+// layer, senders take their request rate from it.
+type Runner struct {
+	s       *Scenario
+	log     *logger.Logger
+	m       PhaseMetrics
+	current atomic.Pointer[Phase]
+}
+
+// NewRunner creates a runner for the scenario.
+func NewRunner(s *Scenario, log *logger.Logger, m PhaseMetrics) *Runner {
+	return &Runner{s: s, log: log, m: m}
+}
+
+// Apply applies the active phase override, if any.
+// Implements behavior.Layer.
+func (r *Runner) Apply(_ *http.Request, p behavior.Profile) behavior.Profile {
+	if phase := r.current.Load(); phase != nil {
+		return phase.Apply(p)
+	}
+	return p
+}
+
+// RPS returns the request rate of the active phase, or base if no phase is
+// active or the phase keeps the configured rate.
+func (r *Runner) RPS(base float64) float64 {
+	if phase := r.current.Load(); phase != nil && phase.RPS != nil {
+		return *phase.RPS
+	}
+	return base
+}
+
+// Run steps through the phases until the scenario ends or the context is
+// cancelled. After the last phase the base behavior is restored unless the
+// scenario repeats.
+func (r *Runner) Run(ctx context.Context) error {
+	r.log.Info("scenario started", "scenario", r.s.Name, "phases", len(r.s.Phases), "repeat", r.s.Repeat)
+	defer r.enter(nil)
+
+	for {
+		for i := range r.s.Phases {
+			phase := &r.s.Phases[i]
+			r.enter(phase)
+
+			select {
+			case <-time.After(phase.Duration):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if !r.s.Repeat {
+			r.log.Info("scenario completed", "scenario", r.s.Name)
+			return nil
+		}
+	}
+}
+
+// enter switches the active phase and updates the phase metric.
+// A nil phase restores the base behavior.
+func (r *Runner) enter(phase *Phase) {
+	prev := r.current.Swap(phase)
+	if prev != nil {
+		r.m.SetScenarioPhase(prev.Name, false)
+	}
+	if phase != nil {
+		r.m.SetScenarioPhase(phase.Name, true)
+		r.log.Info("scenario phase started", "scenario", r.s.Name, "phase", phase.Name, "duration", phase.Duration)
+	}
+}
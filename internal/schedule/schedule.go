@@ -0,0 +1,229 @@
+// Package schedule provides receiver behavior conditioned on wall-clock
+// windows, so long-running environments can exhibit daily patterns.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+
+	"github.com/neox5/tct/internal/behavior"
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// weekdays maps day names to time.Weekday values.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule is a set of wall-clock behavior windows.
+type Schedule struct {
+	Timezone string   `yaml:"timezone"`
+	Windows  []Window `yaml:"windows"`
+
+	loc *time.Location
+}
+
+// Window overrides behavior parameters daily between From and To.
+// A window with To before From spans midnight. Days restricts the window
+// to the listed weekdays (the day the window opens); empty means every day.
+type Window struct {
+	Name              string   `yaml:"name"`
+	From              Clock    `yaml:"from"`
+	To                Clock    `yaml:"to"`
+	Days              []string `yaml:"days"`
+	behavior.Override `yaml:",inline"`
+}
+
+// Clock is a time of day in minutes after midnight, written as "HH:MM".
+type Clock int
+
+// UnmarshalYAML parses an "HH:MM" time of day.
+func (c *Clock) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return fmt.Errorf("invalid time of day %q (must be 'HH:MM')", s)
+	}
+	*c = Clock(t.Hour()*60 + t.Minute())
+	return nil
+}
+
+// Load reads and validates a schedule file. Both YAML and JSON are accepted.
+//
+// Example:
+//
+//	timezone: Europe/Vienna
+//	windows:
+//	  - name: morning-peak
+//	    from: "09:00"
+//	    to: "10:00"
+//	    days: [mon, tue, wed, thu, fri]
+//	    response_delay: 500ms
+//	  - name: nightly-batch
+//	    from: "23:30"
+//	    to: "01:00"
+//	    error_rate: 0.05
+func Load(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Parse parses and validates a schedule document read from source.
+func Parse(data []byte, source string) (*Schedule, error) {
+	s := &Schedule{}
+	if err := yaml.UnmarshalStrict(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule %s: %w", source, err)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid schedule %s: %w", source, err)
+	}
+
+	return s, nil
+}
+
+// validate checks the timezone, window names, days, and override ranges.
+func (s *Schedule) validate() error {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+	if s.Timezone == "" {
+		loc = time.Local
+	}
+	s.loc = loc
+
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("at least one window is required")
+	}
+
+	for i, w := range s.Windows {
+		if w.Name == "" {
+			return fmt.Errorf("window %d: name is required", i)
+		}
+		if w.From == w.To {
+			return fmt.Errorf("window %q: from and to must differ", w.Name)
+		}
+		for _, d := range w.Days {
+			if _, ok := weekdays[strings.ToLower(d)]; !ok {
+				return fmt.Errorf("window %q: invalid day %q (must be 'mon' through 'sun')", w.Name, d)
+			}
+		}
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("window %q: %w", w.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// activeAt reports whether the window is open at t.
+func (w *Window) activeAt(t time.Time) bool {
+	now := Clock(t.Hour()*60 + t.Minute())
+
+	var open bool
+	day := t.Weekday()
+	if w.From < w.To {
+		open = now >= w.From && now < w.To
+	} else {
+		// Spans midnight: after midnight the window opened the day before
+		open = now >= w.From || now < w.To
+		if now < w.To {
+			day = (day + 6) % 7
+		}
+	}
+	if !open || len(w.Days) == 0 {
+		return open
+	}
+
+	return slices.ContainsFunc(w.Days, func(d string) bool {
+		return weekdays[strings.ToLower(d)] == day
+	})
+}
+
+// Layer applies the windows open at request time as a behavior layer.
+// Overlapping windows are applied in file order.
+type Layer struct {
+	s   *Schedule
+	log *logger.Logger
+	m   *metrics.ReceiverMetrics
+}
+
+// NewLayer creates a schedule layer.
+func NewLayer(s *Schedule, log *logger.Logger, m *metrics.ReceiverMetrics) *Layer {
+	return &Layer{s: s, log: log, m: m}
+}
+
+// Apply applies the override of every open window.
+// Implements behavior.Layer.
+func (l *Layer) Apply(_ *http.Request, p behavior.Profile) behavior.Profile {
+	now := time.Now().In(l.s.loc)
+	for i := range l.s.Windows {
+		if w := &l.s.Windows[i]; w.activeAt(now) {
+			p = w.Apply(p)
+		}
+	}
+	return p
+}
+
+// Run tracks window transitions, logging them and updating the window
+// metric, until the context is cancelled.
+func (l *Layer) Run(ctx context.Context) error {
+	l.log.Info("schedule started", "windows", len(l.s.Windows), "timezone", l.s.loc.String())
+
+	active := make([]bool, len(l.s.Windows))
+	defer func() {
+		// Close the open windows, e.g. when the schedule is replaced
+		for i, open := range active {
+			if open {
+				l.m.SetScheduleWindow(l.s.Windows[i].Name, false)
+			}
+		}
+	}()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now().In(l.s.loc)
+		for i := range l.s.Windows {
+			w := &l.s.Windows[i]
+			open := w.activeAt(now)
+			if open == active[i] {
+				continue
+			}
+			active[i] = open
+			l.m.SetScheduleWindow(w.Name, open)
+			if open {
+				l.log.Info("schedule window opened", "window", w.Name)
+			} else {
+				l.log.Info("schedule window closed", "window", w.Name)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
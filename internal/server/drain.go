@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Drain coordinates the graceful termination of traffic servers: once
+// shutdown begins, readiness fails and new requests are rejected with 503
+// and Connection: close for the drain delay, while in-flight requests
+// finish, so load balancers can take the instance out of rotation.
+type Drain struct {
+	delay    time.Duration
+	draining atomic.Bool
+}
+
+// NewDrain creates a drain keeping servers up for delay after shutdown
+// begins.
+func NewDrain(delay time.Duration) *Drain {
+	return &Drain{delay: delay}
+}
+
+// Draining reports whether shutdown has begun. A nil Drain never drains.
+func (d *Drain) Draining() bool {
+	return d != nil && d.draining.Load()
+}
+
+// begin marks the start of shutdown and waits for the drain delay.
+func (d *Drain) begin() {
+	d.draining.Store(true)
+	time.Sleep(d.delay)
+}
+
+// Reject is middleware answering requests with 503 and Connection: close
+// while draining.
+func (d *Drain) Reject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Draining() {
+			w.Header().Set("Connection", "close")
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "draining", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// gateListener is a TCP or Unix socket listener that can be closed and
+// reopened on the same address while the HTTP server keeps serving. While
+// closed, the address is not bound and connection attempts are refused by
+// the OS.
+type gateListener struct {
+	network string
+	addr    string
+	bound   net.Addr
+	mutex   sync.Mutex
+	ln      net.Listener  // nil while closed
+	open    chan struct{} // closed once ln is set
+	done    chan struct{} // closed by Close
+	once    sync.Once
+}
+
+// listen binds addr on the network ("tcp" or "unix") and returns an open
+// gate listener. A stale Unix socket file is removed first. The socket file
+// of a Unix listener is removed when it is closed (net.UnixListener unlinks
+// it by default).
+func listen(network, addr string) (*gateListener, error) {
+	if network == "unix" {
+		if err := removeStaleSocket(addr); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	open := make(chan struct{})
+	close(open)
+	return &gateListener{network: network, addr: addr, bound: ln.Addr(), ln: ln, open: open, done: make(chan struct{})}, nil
+}
+
+// removeStaleSocket removes the socket file at path left behind by a
+// process that did not shut down cleanly, i.e. one refusing connections.
+// A socket still served by a process is an error, so a second instance
+// cannot take over its traffic. Other files are kept, so binding fails
+// instead of deleting them.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use by another process", path)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("socket %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
+
+// Accept waits for the next connection, blocking while the gate is closed.
+func (g *gateListener) Accept() (net.Conn, error) {
+	for {
+		g.mutex.Lock()
+		ln, open := g.ln, g.open
+		g.mutex.Unlock()
+
+		if ln == nil {
+			select {
+			case <-open:
+				continue
+			case <-g.done:
+				return nil, net.ErrClosed
+			}
+		}
+
+		conn, err := ln.Accept()
+		if err != nil {
+			// Listener closed by pause rather than shutdown: wait for reopen
+			g.mutex.Lock()
+			paused := g.ln != ln
+			g.mutex.Unlock()
+			select {
+			case <-g.done:
+				return nil, net.ErrClosed
+			default:
+			}
+			if paused {
+				continue
+			}
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// pause unbinds the port so new connections are refused.
+// No-op if already paused.
+func (g *gateListener) pause() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.ln == nil {
+		return nil
+	}
+	err := g.ln.Close()
+	g.ln = nil
+	g.open = make(chan struct{})
+	return err
+}
+
+// resume binds the port again. No-op if not paused.
+func (g *gateListener) resume() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.ln != nil {
+		return nil
+	}
+	ln, err := net.Listen(g.network, g.addr)
+	if err != nil {
+		return err
+	}
+	g.ln = ln
+	close(g.open)
+	return nil
+}
+
+// Close stops the listener permanently.
+func (g *gateListener) Close() error {
+	g.once.Do(func() { close(g.done) })
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.ln == nil {
+		return nil
+	}
+	return g.ln.Close()
+}
+
+// Addr returns the address the listener was first bound to.
+func (g *gateListener) Addr() net.Addr {
+	return g.bound
+}
+
+// limitListener limits the number of open connections accepted from a
+// listener. Further connections wait in the accept backlog until an open
+// one is closed.
+type limitListener struct {
+	net.Listener
+	sem  chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// limit wraps ln to accept at most n open connections.
+func limit(ln net.Listener, n int) *limitListener {
+	return &limitListener{Listener: ln, sem: make(chan struct{}, n), done: make(chan struct{})}
+}
+
+// Accept waits for a free connection slot, then for the next connection.
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitConn{Conn: conn, release: func() { <-l.sem }}, nil
+}
+
+// Close stops the listener and unblocks waiting Accept calls.
+func (l *limitListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// limitConn frees its connection slot when closed.
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+// Close closes the connection and frees its slot.
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
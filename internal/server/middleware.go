@@ -0,0 +1,104 @@
+package server
+
+import (
+	"crypto/rand"
+	"net/http"
+	"time"
+
+	"github.com/neox5/tct/internal/logger"
+	"github.com/neox5/tct/internal/random"
+)
+
+// RequestIDHeader carries the ID of a request.
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware wraps a handler with cross-cutting behavior (e.g. tracing,
+// metrics, panic recovery), so it is not reimplemented in each handler.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with the middleware. The first middleware is the outermost,
+// i.e. sees the request first.
+func Chain(h http.Handler, mws ...Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// RequestID is middleware that makes sure each request carries an ID in the
+// X-Request-ID header, keeping the ID set by the client or a proxy and
+// generating one otherwise. The ID is echoed in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = rand.Text()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AccessLog returns middleware logging one line per request for the
+// fraction rate of requests sampled by rng, so traffic can be audited
+// without debug logging.
+func AccessLog(log *logger.Logger, rate float64, rng *random.Rand) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rng.Float64() >= rate {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rw := &statusWriter{ResponseWriter: w}
+			defer func() {
+				log.Info("http request",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"proto", r.Proto,
+					"remote", r.RemoteAddr,
+					"status", rw.status,
+					"bytes", rw.bytes,
+					"duration_ms", float64(time.Since(start))/float64(time.Millisecond),
+					"request_id", w.Header().Get(RequestIDHeader),
+				)
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// statusWriter records the status and body size of a response. A status
+// of 0 means no response was written.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the status and writes the header.
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body size, writing an implicit 200 header first.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
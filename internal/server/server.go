@@ -3,65 +3,282 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/neox5/tct/internal/logger"
-	"github.com/neox5/tct/internal/metrics"
 )
 
 // Server manages the HTTP server lifecycle.
 type Server struct {
+	host   string
 	port   int
+	socket string // Unix socket path (replaces host and port if set)
 	logger *logger.Logger
 	mux    *http.ServeMux
+	srv    *http.Server
+	tls    *tls.Config
+	http2  bool
+	mutex  sync.Mutex
+	ln     *gateListener // nil until Start binds the port
+	paused bool
+	mws    []Middleware // applied to handlers registered afterwards
+	drain  *Drain       // nil to shut down immediately
+
+	shutdownTimeout time.Duration
+
+	maxConns int                         // 0 = unlimited
+	conns    map[net.Conn]http.ConnState // open connections by state
+	connsMu  sync.Mutex
+}
+
+// ConnStats counts the open connections of a server by state. New
+// connections have not sent a request yet.
+type ConnStats struct {
+	New    int
+	Active int
+	Idle   int
+}
+
+// Timeouts are the http.Server timeouts. Zero values disable the timeout
+// (IdleTimeout falls back to ReadTimeout). Shutdown bounds the wait for
+// in-flight requests on shutdown (0 = wait until they complete).
+type Timeouts struct {
+	Read       time.Duration
+	ReadHeader time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	Shutdown   time.Duration
 }
 
-// New creates a new HTTP server.
-func New(port int, log *logger.Logger) *Server {
+// New creates a new HTTP server listening on host (all interfaces if empty)
+// and port with the given timeouts.
+func New(host string, port int, log *logger.Logger, timeouts Timeouts) *Server {
 	return &Server{
+		host:   strings.Trim(host, "[]"),
 		port:   port,
 		logger: log,
 		mux:    http.NewServeMux(),
+		conns:  map[net.Conn]http.ConnState{},
+
+		shutdownTimeout: timeouts.Shutdown,
+		srv: &http.Server{
+			ReadTimeout:       timeouts.Read,
+			ReadHeaderTimeout: timeouts.ReadHeader,
+			WriteTimeout:      timeouts.Write,
+			IdleTimeout:       timeouts.Idle,
+		},
 	}
 }
 
+// NewUnix creates a new HTTP server listening on the Unix socket at path
+// with the given timeouts. A stale socket file left behind by a crashed
+// process is replaced; the socket file is removed on shutdown (see listen).
+func NewUnix(path string, log *logger.Logger, timeouts Timeouts) *Server {
+	s := New("", 0, log, timeouts)
+	s.socket = path
+	return s
+}
+
+// Use adds middleware applied to all handlers registered afterwards, after
+// (outside of) their own middleware.
+func (s *Server) Use(mws ...Middleware) {
+	s.mws = append(s.mws, mws...)
+}
+
 // RegisterCommonRoutes registers /metrics, /healthz, and /readyz endpoints.
-func (s *Server) RegisterCommonRoutes(healthz, readyz http.HandlerFunc) {
-	s.mux.Handle("GET /metrics", metrics.Handler())
-	s.mux.HandleFunc("GET /healthz", healthz)
-	s.mux.HandleFunc("GET /readyz", readyz)
+func (s *Server) RegisterCommonRoutes(metrics http.Handler, healthz, readyz http.HandlerFunc) {
+	s.mux.Handle("GET /metrics", Chain(metrics, s.mws...))
+	s.mux.Handle("GET /healthz", Chain(healthz, s.mws...))
+	s.mux.Handle("GET /readyz", Chain(readyz, s.mws...))
+}
+
+// RegisterPprof registers the net/http/pprof profiling endpoints under
+// /debug/pprof/.
+func (s *Server) RegisterPprof() {
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+}
+
+// SetTLSConfig enables HTTPS using the given TLS configuration.
+// The configuration must provide certificates (e.g. via GetCertificate).
+func (s *Server) SetTLSConfig(cfg *tls.Config) {
+	s.tls = cfg
+}
+
+// EnableHTTP2 enables HTTP/2 on cleartext connections (h2c with prior
+// knowledge). HTTPS connections negotiate HTTP/2 via ALPN regardless.
+func (s *Server) EnableHTTP2() {
+	s.http2 = true
+}
+
+// SetDrain makes the server drain on shutdown: d starts draining and the
+// server keeps serving for its delay before shutting down.
+func (s *Server) SetDrain(d *Drain) {
+	s.drain = d
+}
+
+// SetMaxConnections limits the number of open connections (0 = unlimited).
+// Further connections wait in the accept backlog until one is closed.
+// Takes effect when the server starts.
+func (s *Server) SetMaxConnections(n int) {
+	s.maxConns = n
+}
+
+// ConnStats returns the current connection counts.
+func (s *Server) ConnStats() ConnStats {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	var stats ConnStats
+	for _, state := range s.conns {
+		switch state {
+		case http.StateNew:
+			stats.New++
+		case http.StateActive:
+			stats.Active++
+		case http.StateIdle:
+			stats.Idle++
+		}
+	}
+	return stats
+}
+
+// trackConn records connection state changes for ConnStats.
+func (s *Server) trackConn(c net.Conn, state http.ConnState) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	switch state {
+	case http.StateClosed, http.StateHijacked:
+		delete(s.conns, c)
+	default:
+		s.conns[c] = state
+	}
+}
+
+// SetKeepAlivesEnabled controls whether HTTP keep-alives are enabled.
+// Disabling closes idle connections and makes the server close each
+// connection after its response. Safe to call while the server is running.
+func (s *Server) SetKeepAlivesEnabled(enabled bool) {
+	s.srv.SetKeepAlivesEnabled(enabled)
+}
+
+// SetConnContext sets a hook that derives the base context of each
+// accepted connection, e.g. to tag connections for per-connection faults.
+func (s *Server) SetConnContext(fn func(ctx context.Context, c net.Conn) context.Context) {
+	s.srv.ConnContext = fn
+}
+
+// SetAccepting binds (true) or unbinds (false) the listening port while the
+// server keeps running, so new connections are refused while not accepting.
+// Established connections are not affected. Safe to call at any time; takes
+// effect once the server has started.
+func (s *Server) SetAccepting(accepting bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.paused = !accepting
+	if s.ln == nil {
+		return
+	}
+
+	var err error
+	if accepting {
+		err = s.ln.resume()
+	} else {
+		err = s.ln.pause()
+	}
+	if err != nil {
+		s.logger.Error("listener state change failed", "accepting", accepting, "error", err)
+		return
+	}
+	s.logger.Info("listener state changed", "addr", s.srv.Addr, "accepting", accepting)
 }
 
-// RegisterHandler registers a custom HTTP handler.
-func (s *Server) RegisterHandler(pattern string, handler http.HandlerFunc) {
-	s.mux.HandleFunc(pattern, handler)
+// RegisterHandler registers a custom HTTP handler wrapped with the given
+// middleware and the middleware of the server (see Use).
+func (s *Server) RegisterHandler(pattern string, handler http.HandlerFunc, mws ...Middleware) {
+	s.mux.Handle(pattern, Chain(Chain(handler, mws...), s.mws...))
 }
 
 // Start runs the HTTP server with graceful shutdown support.
-// Blocks until the server stops or an error occurs.
+// Blocks until the server fails or, once ctx is cancelled, graceful
+// shutdown has completed; a shutdown error (e.g. the timeout expiring with
+// requests still in flight) is returned.
 func (s *Server) Start(ctx context.Context) error {
-	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: s.mux,
+	srv := s.srv
+	network := "tcp"
+	srv.Addr = net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	if s.socket != "" {
+		network, srv.Addr = "unix", s.socket
+	}
+	srv.Handler = s.mux
+	srv.TLSConfig = s.tls
+	srv.ConnState = s.trackConn
+	if s.http2 {
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetHTTP2(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
 	}
 
 	// Graceful shutdown handler
+	shutdown := make(chan error, 1)
 	go func() {
 		<-ctx.Done()
-		s.logger.Info("shutting down server")
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := srv.Shutdown(shutdownCtx); err != nil {
-			s.logger.Error("server shutdown error", "error", err)
+		if s.drain != nil {
+			if s.drain.delay > 0 {
+				s.logger.Info("draining server", "addr", srv.Addr, "delay", s.drain.delay)
+			}
+			s.drain.begin()
 		}
+		s.logger.Info("shutting down server", "addr", srv.Addr)
+		shutdownCtx := context.Background()
+		if s.shutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.shutdownTimeout)
+			defer cancel()
+		}
+		shutdown <- srv.Shutdown(shutdownCtx)
 	}()
 
-	s.logger.Info("starting server", "port", s.port)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	ln, err := listen(network, srv.Addr)
+	if err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+	s.mutex.Lock()
+	s.ln = ln
+	if s.paused {
+		ln.pause()
+	}
+	s.mutex.Unlock()
+
+	var l net.Listener = ln
+	if s.maxConns > 0 {
+		l = limit(ln, s.maxConns)
+	}
+
+	s.logger.Info("starting server", "addr", srv.Addr, "tls", s.tls != nil, "http2", s.http2, "max_connections", s.maxConns)
+	if s.tls != nil {
+		err = srv.ServeTLS(l, "", "")
+	} else {
+		err = srv.Serve(l)
+	}
+	if err != http.ErrServerClosed {
 		return fmt.Errorf("server error: %w", err)
 	}
 
+	// Serve returns as soon as shutdown begins; wait for in-flight requests
+	if err := <-shutdown; err != nil {
+		return fmt.Errorf("server shutdown error: %w", err)
+	}
 	return nil
 }
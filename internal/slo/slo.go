@@ -0,0 +1,91 @@
+// Package slo tracks service level objective compliance and computes the
+// error budget burn rate over sliding windows, for testing multi-window
+// burn-rate alerts against controlled failure injection.
+package slo
+
+import (
+	"sync"
+	"time"
+)
+
+// slotWidth is the time resolution of the sliding windows.
+const slotWidth = 10 * time.Second
+
+// slot counts the events of one slotWidth interval.
+type slot struct {
+	start time.Time
+	total float64
+	bad   float64
+}
+
+// Tracker counts good and bad events over the longest window. An event is
+// good if it succeeded and, with a latency objective, was fast enough.
+// A nil Tracker ignores events. It is safe for concurrent use.
+type Tracker struct {
+	objective float64       // target fraction of good events
+	latency   time.Duration // maximum latency of good events (0 for none)
+
+	mu    sync.Mutex
+	slots []slot // ring buffer covering the longest window
+}
+
+// New creates a tracker for the objective (e.g. 0.999) that keeps events for
+// the longest window. A positive latency makes slower events bad.
+func New(objective float64, latency time.Duration, longest time.Duration) *Tracker {
+	n := int(longest/slotWidth) + 1
+	return &Tracker{objective: objective, latency: latency, slots: make([]slot, n)}
+}
+
+// Record counts an event that succeeded (ok) or failed after latency.
+func (t *Tracker) Record(ok bool, latency time.Duration) {
+	if t == nil {
+		return
+	}
+	bad := !ok || (t.latency > 0 && latency > t.latency)
+
+	now := time.Now()
+	start := now.Truncate(slotWidth)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &t.slots[int(start.Unix()/int64(slotWidth/time.Second))%len(t.slots)]
+	if !s.start.Equal(start) {
+		*s = slot{start: start}
+	}
+	s.total++
+	if bad {
+		s.bad++
+	}
+}
+
+// Reset discards all events, e.g. when the metrics are reset for a new
+// experiment.
+func (t *Tracker) Reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.slots)
+}
+
+// BurnRate returns the rate at which the error budget is spent over the
+// window ending now: the fraction of bad events divided by the allowed
+// fraction. 1 spends the budget exactly over the SLO period; 0 without
+// events.
+func (t *Tracker) BurnRate(window time.Duration) float64 {
+	since := time.Now().Add(-window)
+	var total, bad float64
+	t.mu.Lock()
+	for _, s := range t.slots {
+		if !s.start.IsZero() && s.start.After(since) {
+			total += s.total
+			bad += s.bad
+		}
+	}
+	t.mu.Unlock()
+
+	if total == 0 {
+		return 0
+	}
+	return bad / total / (1 - t.objective)
+}
@@ -0,0 +1,101 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// producer converts the metrics of a Prometheus registry to OpenTelemetry
+// metric data, so both exports carry the same instruments.
+// Counters become monotonic sums, gauges become gauges, and histograms keep
+// their classic buckets. Summaries and untyped metrics are not exported.
+type producer struct {
+	gatherer prometheus.Gatherer
+	start    time.Time
+}
+
+// newProducer creates a producer for the metrics gathered by g.
+func newProducer(g prometheus.Gatherer) *producer {
+	return &producer{gatherer: g, start: time.Now()}
+}
+
+// Produce implements sdkmetric.Producer.
+func (p *producer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return nil, err
+	}
+
+	now := time.Now()
+	var out []metricdata.Metrics
+	for _, mf := range families {
+		var data metricdata.Aggregation
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+			for _, m := range mf.GetMetric() {
+				sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: attributes(m), StartTime: p.start, Time: now, Value: m.GetCounter().GetValue(),
+				})
+			}
+			data = sum
+		case dto.MetricType_GAUGE:
+			gauge := metricdata.Gauge[float64]{}
+			for _, m := range mf.GetMetric() {
+				gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: attributes(m), Time: now, Value: m.GetGauge().GetValue(),
+				})
+			}
+			data = gauge
+		case dto.MetricType_HISTOGRAM:
+			hist := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+			for _, m := range mf.GetMetric() {
+				hist.DataPoints = append(hist.DataPoints, histogramPoint(m.GetHistogram(), attributes(m), p.start, now))
+			}
+			data = hist
+		default:
+			continue
+		}
+		out = append(out, metricdata.Metrics{Name: mf.GetName(), Description: mf.GetHelp(), Data: data})
+	}
+
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: "github.com/neox5/tct"},
+		Metrics: out,
+	}}, err
+}
+
+// histogramPoint converts cumulative Prometheus buckets to per-bucket counts
+// with a final overflow bucket.
+func histogramPoint(h *dto.Histogram, attrs attribute.Set, start, now time.Time) metricdata.HistogramDataPoint[float64] {
+	pt := metricdata.HistogramDataPoint[float64]{
+		Attributes: attrs,
+		StartTime:  start,
+		Time:       now,
+		Count:      h.GetSampleCount(),
+		Sum:        h.GetSampleSum(),
+	}
+	var prev uint64
+	for _, b := range h.GetBucket() {
+		pt.Bounds = append(pt.Bounds, b.GetUpperBound())
+		pt.BucketCounts = append(pt.BucketCounts, b.GetCumulativeCount()-prev)
+		prev = b.GetCumulativeCount()
+	}
+	pt.BucketCounts = append(pt.BucketCounts, pt.Count-prev)
+	return pt
+}
+
+// attributes converts the labels of a metric to attributes.
+func attributes(m *dto.Metric) attribute.Set {
+	kvs := make([]attribute.KeyValue, len(m.GetLabel()))
+	for i, l := range m.GetLabel() {
+		kvs[i] = attribute.String(l.GetName(), l.GetValue())
+	}
+	return attribute.NewSet(kvs...)
+}
@@ -0,0 +1,89 @@
+// Package telemetry exports metrics and traces to an OpenTelemetry collector
+// via OTLP, for environments that collect telemetry by push instead of
+// scraping.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/neox5/tct/internal/version"
+)
+
+// Provider exports telemetry until shut down.
+type Provider struct {
+	meters *sdkmetric.MeterProvider
+	traces *sdktrace.TracerProvider
+}
+
+// New starts exporting the Prometheus metrics gathered by g every interval
+// and the spans of the given fraction of traces (see Tracer) to the
+// OTLP/HTTP collector at the base URL endpoint (signals are sent to
+// /v1/metrics and /v1/traces below it). If endpoint is nil, the standard
+// OTEL_EXPORTER_OTLP_* environment variables apply (default
+// localhost:4318). Telemetry is attributed to service tct in the given mode.
+func New(ctx context.Context, g prometheus.Gatherer, endpoint *url.URL, interval time.Duration, traceRatio float64, mode string) (*Provider, error) {
+	var metricOpts []otlpmetrichttp.Option
+	var traceOpts []otlptracehttp.Option
+	if endpoint != nil {
+		metricOpts = append(metricOpts, otlpmetrichttp.WithEndpointURL(endpoint.JoinPath("v1", "metrics").String()))
+		traceOpts = append(traceOpts, otlptracehttp.WithEndpointURL(endpoint.JoinPath("v1", "traces").String()))
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res := newResource(mode)
+	reader := sdkmetric.NewPeriodicReader(metricExporter,
+		sdkmetric.WithInterval(interval),
+		sdkmetric.WithProducer(newProducer(g)),
+	)
+	meters := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(res),
+	)
+	traces := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithResource(res),
+		// Follow the sampling decision of the caller, if any
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(traceRatio))),
+	)
+
+	// Install globally so Tracer and trace propagation pick them up
+	otel.SetTracerProvider(traces)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return &Provider{meters: meters, traces: traces}, nil
+}
+
+// Shutdown flushes pending telemetry and stops exporting.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return errors.Join(p.traces.Shutdown(ctx), p.meters.Shutdown(ctx))
+}
+
+// newResource describes the process to the collector.
+func newResource(mode string) *resource.Resource {
+	return resource.NewSchemaless(
+		attribute.String("service.name", "tct"),
+		attribute.String("service.version", version.String()),
+		attribute.String("tct.mode", mode),
+	)
+}
@@ -0,0 +1,72 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scope is the instrumentation scope of tct telemetry.
+const scope = "github.com/neox5/tct"
+
+// Tracer returns the tracer for tct spans. Spans are not recorded unless New
+// has installed a tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(scope)
+}
+
+// StartClient starts a client span for an outgoing request and propagates
+// it to the server in the request headers.
+func StartClient(req *http.Request) (*http.Request, trace.Span) {
+	ctx, span := Tracer().Start(req.Context(), req.Method+" "+req.URL.Path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", req.URL.Redacted()),
+		),
+	)
+	req = req.WithContext(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return req, span
+}
+
+// Handler is middleware handling each request in a server span, continuing
+// the trace propagated by the client, if any. The span is named after the
+// matched route.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, r.Pattern,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Annotate records the outcome of a request on the span of ctx. A status of 0
+// marks requests without a response; 5xx responses and failures without a
+// response mark the span as failed.
+func Annotate(ctx context.Context, status int, attrs ...attribute.KeyValue) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(attrs...)
+	if status != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+	}
+	if status == 0 || status >= 500 {
+		span.SetStatus(codes.Error, "")
+	}
+}
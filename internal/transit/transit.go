@@ -0,0 +1,32 @@
+// Package transit measures the delay between a sender sending a request and
+// the receiver handling it, via a header carrying the send time.
+//
+// The delay is the difference of two clocks, so it is only meaningful if the
+// sender and receiver clocks are synchronized (e.g. via NTP); clock skew
+// shifts every measurement and can make delays negative.
+package transit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header carries the send time of a request in Unix nanoseconds.
+const Header = "X-TCT-Sent-At"
+
+// Set writes the send time t to h.
+func Set(h http.Header, t time.Time) {
+	h.Set(Header, strconv.FormatInt(t.UnixNano(), 10))
+}
+
+// Since returns the time elapsed since the request was sent, if the request
+// carries a valid send time. The result is negative if the sender clock is
+// ahead by more than the transit delay.
+func Since(r *http.Request) (time.Duration, bool) {
+	ns, err := strconv.ParseInt(r.Header.Get(Header), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Since(time.Unix(0, ns)), true
+}
@@ -0,0 +1,94 @@
+// Package verdict evaluates assertions on the requests of a sender run, so
+// tct can serve as a pass/fail gate in CI pipelines.
+package verdict
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/neox5/tct/internal/metrics"
+)
+
+// Assertions are the conditions a run must meet. Zero values (an error rate
+// of 1) do not constrain the run.
+type Assertions struct {
+	MaxErrorRate float64       // maximum fraction of non-ok requests
+	MaxP99       time.Duration // maximum p99 latency (0 for none)
+	NoResults    []string      // results that must not occur
+}
+
+// Enabled reports whether any assertion is configured.
+func (a Assertions) Enabled() bool {
+	return a.MaxErrorRate < 1 || a.MaxP99 > 0 || len(a.NoResults) > 0
+}
+
+// Check is the outcome of one assertion.
+type Check struct {
+	Name  string  `json:"name"`
+	Limit float64 `json:"limit"`
+	Value float64 `json:"value"`
+	Pass  bool    `json:"pass"`
+}
+
+// Verdict is the machine-readable outcome of a run.
+type Verdict struct {
+	Pass    bool            `json:"pass"`
+	Checks  []Check         `json:"checks"`
+	Summary metrics.Summary `json:"summary"`
+}
+
+// Evaluate checks the assertions against the summary of a run. A run
+// without requests fails, as it cannot demonstrate anything.
+func Evaluate(a Assertions, sum metrics.Summary) Verdict {
+	v := Verdict{Pass: true, Summary: sum}
+	check := func(name string, limit, value float64, pass bool) {
+		v.Checks = append(v.Checks, Check{Name: name, Limit: limit, Value: value, Pass: pass})
+		v.Pass = v.Pass && pass
+	}
+
+	check("min_requests", 1, sum.Requests, sum.Requests >= 1)
+	if a.MaxErrorRate < 1 {
+		var rate float64
+		if sum.Requests > 0 {
+			rate = (sum.Requests - sum.Results["ok"]) / sum.Requests
+		}
+		check("max_error_rate", a.MaxErrorRate, rate, rate <= a.MaxErrorRate)
+	}
+	if a.MaxP99 > 0 {
+		// A p99 above the largest bucket bound is unknown and fails; the
+		// latency buckets include MaxP99 as bound (see metrics.Options)
+		limit := float64(a.MaxP99) / float64(time.Millisecond)
+		check("max_p99_ms", limit, sum.P99Ms, !sum.P99Overflow && sum.P99Ms <= limit)
+	}
+	for _, result := range slices.Sorted(slices.Values(a.NoResults)) {
+		n := sum.Results[result]
+		check("no_"+result, 0, n, n == 0)
+	}
+	return v
+}
+
+// Write writes the verdict as JSON to dest, either "stderr", "stdout", or
+// a file path (replaced if it exists). Logs go to stdout, so the verdict
+// can only be told apart from them on stderr or in a file.
+func (v Verdict) Write(dest string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	switch dest {
+	case "stderr":
+		_, err = os.Stderr.Write(data)
+		return err
+	case "stdout":
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(dest, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write verdict: %w", err)
+	}
+	return nil
+}